@@ -0,0 +1,120 @@
+package rest
+
+import (
+	"container/list"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores raw response bodies of successful calls.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// WithCache caches responses of the endpoints listed in ttls. The keys of ttls are endpoint paths,
+// e.g. /stable/profile, and endpoints that are not listed are never cached.
+func WithCache(cache Cache, ttls map[string]time.Duration) Option {
+	return func(c *Client) {
+		c.cache = cache
+		c.cacheTTLs = ttls
+	}
+}
+
+// LRUCache is an in-memory Cache that evicts the least recently used entry once it is full.
+type LRUCache struct {
+	capacity int
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCache returns an in-memory cache holding at most capacity entries.
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value stored under the key if it has not expired yet.
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores the value under the key for the ttl.
+func (c *LRUCache) Set(key string, value []byte, ttl time.Duration) {
+	if c.capacity <= 0 || ttl <= 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of entries in the cache, including the expired ones that have not been evicted yet.
+func (c *LRUCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
+
+// cacheKey builds the cache key of a request from its method, URI and extra query params.
+// The API key is left out so that it never ends up in the cache.
+func cacheKey(method, uri string, queryParams url.Values) string {
+	path, rawQuery, _ := strings.Cut(uri, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return method + " " + uri
+	}
+	for k, vs := range queryParams {
+		for _, v := range vs {
+			query.Add(k, v)
+		}
+	}
+	query.Del("apikey")
+	if len(query) == 0 {
+		return method + " " + path
+	}
+	return method + " " + path + "?" + query.Encode()
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2)
+
+	cache.Set("a", []byte("1"), time.Minute)
+	cache.Set("b", []byte("2"), time.Minute)
+	_, ok := cache.Get("a") // "a" becomes the most recently used entry
+	require.True(t, ok)
+	cache.Set("c", []byte("3"), time.Minute)
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	v, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Set("d", []byte("4"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	_, ok = cache.Get("d")
+	assert.False(t, ok, "expired entry should not be returned")
+}
+
+func TestCacheKey(t *testing.T) {
+	key := cacheKey(http.MethodGet, "/stable/quote?symbol=AAPL&apikey=secret", url.Values{"short": {"false"}})
+	assert.Equal(t, "GET /stable/quote?short=false&symbol=AAPL", key)
+	assert.Equal(t, "GET /stable/index-list", cacheKey(http.MethodGet, "/stable/index-list", nil))
+}
+
+func TestClient_CallCache(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"symbol": "AAPL"}]`))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, WithCache(NewLRUCache(10), map[string]time.Duration{"/stable/profile": time.Hour}))
+	ctx := context.Background()
+	params := struct {
+		Symbol string `query:"symbol"`
+	}{Symbol: "AAPL"}
+
+	call := func(path string, opts ...model.RequestOption) {
+		t.Helper()
+		var res []struct {
+			Symbol string `json:"symbol"`
+		}
+		_, err := c.Call(ctx, http.MethodGet, path, params, &res, opts...)
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, "AAPL", res[0].Symbol)
+	}
+
+	call("/stable/profile")
+	call("/stable/profile")
+	assert.Equal(t, int32(1), calls.Load(), "second call should be served from the cache")
+
+	call("/stable/profile", model.WithCacheBypass())
+	assert.Equal(t, int32(2), calls.Load(), "bypass should skip the cache")
+
+	call("/stable/profile", model.WithCacheRefresh())
+	call("/stable/profile")
+	assert.Equal(t, int32(3), calls.Load(), "refresh should call the API and update the cache")
+
+	call("/stable/quote")
+	call("/stable/quote")
+	assert.Equal(t, int32(5), calls.Load(), "endpoints without a TTL should not be cached")
+}
@@ -3,14 +3,21 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/trace"
 
 	"go.tradeforge.dev/fmp/encoder"
+	fmperrors "go.tradeforge.dev/fmp/errors"
 	"go.tradeforge.dev/fmp/model"
 )
 
@@ -22,31 +29,98 @@ const (
 	DefaultClientTimeout = 300 * time.Second
 )
 
+// Option changes the configuration of the Client.
+type Option func(c *Client)
+
+// WithRateLimiter throttles every call made by the client with the given limiter.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
 func New(
 	apiKey string,
 	logger *slog.Logger,
+	opts ...Option,
 ) *Client {
-	c := resty.New()
+	client := &Client{
+		encoder:     encoder.New(),
+		logger:      logger,
+		retryPolicy: DefaultRetryPolicy(),
+		httpConfig: httpConfig{
+			baseURL:   apiURL,
+			userAgent: fmt.Sprintf("Tradeforge client/%v", clientVersion),
+		},
+	}
+	for _, o := range opts {
+		o(client)
+	}
+	client.HTTP = newHTTPClient(apiKey, client.httpConfig)
 
-	c.SetBaseURL(apiURL)
-	c.SetRetryCount(DefaultRetryCount)
-	c.SetTimeout(DefaultClientTimeout)
-	c.SetHeader("User-Agent", fmt.Sprintf("Tradeforge client/%v", clientVersion))
-	c.SetHeader("Accept", "application/json")
-	c.SetQueryParam("apikey", apiKey)
+	middlewares := append([]Middleware{TraceMiddleware(logger)}, client.middlewares...)
+	if client.tracer != nil {
+		middlewares = append(middlewares, telemetryMiddleware)
+	}
+	client.roundTrip = chain(roundTrip, middlewares...)
+	return client
+}
 
-	return &Client{
-		HTTP:    c,
-		encoder: encoder.New(),
-		logger:  logger,
+func newHTTPClient(apiKey string, config httpConfig) *resty.Client {
+	var c *resty.Client
+	if config.httpClient != nil {
+		// The client is copied, so that configuring it does not change the one of the caller, e.g. http.DefaultClient.
+		hc := *config.httpClient
+		c = resty.NewWithClient(&hc)
+	} else {
+		c = resty.New()
+		c.SetTimeout(DefaultClientTimeout)
+	}
+	if config.transport != nil {
+		c.SetTransport(config.transport)
+	}
+	if config.timeout > 0 {
+		c.SetTimeout(config.timeout)
 	}
+	if config.proxyURL != "" {
+		// The proxy is set on the transport, which is cloned for the same reason.
+		if transport, err := c.Transport(); err == nil {
+			c.SetTransport(transport.Clone())
+		}
+		c.SetProxy(config.proxyURL)
+	}
+
+	c.SetBaseURL(config.baseURL)
+	c.SetHeader("User-Agent", config.userAgent)
+	c.SetHeader("Accept", "application/json")
+	c.SetQueryParam("apikey", apiKey)
+	return c
 }
 
-// Client defines an HTTP client for the Polygon REST API.
+// Client defines an HTTP client for the FMP REST API.
 type Client struct {
-	HTTP    *resty.Client
-	encoder *encoder.Encoder
-	logger  *slog.Logger
+	HTTP        *resty.Client
+	encoder     *encoder.Encoder
+	logger      *slog.Logger
+	limiter     RateLimiter
+	retryPolicy RetryPolicy
+	cache       Cache
+	cacheTTLs   map[string]time.Duration
+	flights     flightGroup
+	middlewares []Middleware
+	roundTrip   RoundTrip
+	tracer      trace.Tracer
+	metrics     *clientMetrics
+	httpConfig  httpConfig
+}
+
+// RemainingBudget returns the number of calls that can be made right now without being throttled.
+// It returns false if the client is not rate limited.
+func (c *Client) RemainingBudget() (Budget, bool) {
+	if c.limiter == nil {
+		return Budget{}, false
+	}
+	return c.limiter.Remaining(), true
 }
 
 // Call makes an API call based on the request params and options. The response is automatically unmarshaled.
@@ -55,70 +129,240 @@ func (c *Client) Call(ctx context.Context, method, path string, params, response
 	if err != nil {
 		return nil, fmt.Errorf("encoding params: %w", err)
 	}
-	return c.CallURL(ctx, method, uri, response, opts...)
+	return c.call(ctx, method, path, uri, response, mergeOptions(opts...))
 }
 
 // CallURL makes an API call based on a request URI and options. The response is automatically unmarshaled.
 func (c *Client) CallURL(ctx context.Context, method, uri string, response any, opts ...model.RequestOption) (*resty.Response, error) {
+	return c.call(ctx, method, endpointPath(uri), uri, response, mergeOptions(opts...))
+}
+
+// Open makes an API call based on the request params and options and returns the response without reading its
+// body, so that large responses can be consumed as they arrive instead of being held in memory. The caller must
+// close the body.
+//
+// Streamed responses are neither cached nor shared between identical calls. Responses with an ignored error status
+// code are returned with an empty body. The telemetry of the call is recorded once the body is closed.
+func (c *Client) Open(ctx context.Context, method, path string, params any, opts ...model.RequestOption) (*resty.Response, io.ReadCloser, error) {
+	uri, err := c.encoder.EncodeParams(path, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding params: %w", err)
+	}
 	options := mergeOptions(opts...)
+	options.Stream = true
 
-	c.HTTP.SetTimeout(DefaultClientTimeout)
-	req := c.HTTP.R().SetContext(ctx)
-	if options.Body != nil {
-		b, err := json.Marshal(options.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal body: %w", err)
+	cancel := context.CancelFunc(func() {})
+	if options.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+	}
+	ctx, observation := c.observe(ctx, method, path)
+	res, err := c.execute(ctx, method, path, uri, options)
+	if err != nil {
+		defer cancel()
+		var responseError *fmperrors.ResponseError
+		if res == nil || !errors.As(err, &responseError) {
+			observation.end(ctx, res, err)
+			return nil, nil, fmt.Errorf("failed to execute request: %w", err)
 		}
-		req.SetBody(b)
+		if slices.Contains(options.IgnoredErrorStatusCodes, responseError.StatusCode) {
+			observation.end(ctx, res, nil)
+			return res, http.NoBody, nil
+		}
+		observation.end(ctx, res, err)
+		c.logResponseError(responseError)
+		return res, nil, err
 	}
-	req.SetQueryParamsFromValues(options.QueryParams)
-	req.SetHeaderMultiValues(options.Headers)
-	req.SetResult(response).SetError(&model.ResponseError{})
-	req.SetHeader("Content-Type", options.ContentType)
+	return res, &streamedBody{
+		ReadCloser: res.RawBody(),
+		cancel:     cancel,
+		onClose: func(size int64) {
+			observation.endWithSize(ctx, res, size, nil)
+		},
+	}, nil
+}
 
-	res, err := req.Execute(method, uri)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+// call makes an API call. The endpoint is the path the request URI was built from and identifies the endpoint
+// regardless of the request params.
+func (c *Client) call(ctx context.Context, method, endpoint, uri string, response any, options *model.RequestOptions) (*resty.Response, error) {
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	ctx, observation := c.observe(ctx, method, endpoint)
+	res, err := c.doCall(ctx, method, endpoint, uri, response, options, observation)
+	observation.end(ctx, res, err)
+	return res, err
+}
+
+func (c *Client) doCall(
+	ctx context.Context,
+	method, endpoint, uri string,
+	response any,
+	options *model.RequestOptions,
+	observation *callObservation,
+) (*resty.Response, error) {
+	key, ttl, cacheable := c.cachePolicy(method, endpoint, uri, options)
+	if cacheable && options.CacheControl == model.CacheControlDefault {
+		if body, ok := c.cache.Get(key); ok {
+			observation.markCacheHit()
+			return c.cachedResponse(ctx, body, response)
+		}
+	}
+
+	var (
+		res *resty.Response
+		err error
+	)
+	if coalescable(method, options) {
+		// Identical calls in flight share a single request, each caller decodes the shared body on its own.
+		res, err = c.flights.do(ctx, cacheKey(method, uri, options.QueryParams), func(ctx context.Context) (*resty.Response, error) {
+			return c.execute(ctx, method, endpoint, uri, options)
+		})
+	} else {
+		res, err = c.execute(ctx, method, endpoint, uri, options)
 	}
-	if res.IsError() {
-		if slices.Contains(options.IgnoredErrorStatusCodes, res.StatusCode()) {
+	if err != nil {
+		var responseError *fmperrors.ResponseError
+		if res == nil || !errors.As(err, &responseError) {
+			return nil, fmt.Errorf("failed to execute request: %w", err)
+		}
+		if slices.Contains(options.IgnoredErrorStatusCodes, responseError.StatusCode) {
 			return res, nil
 		}
-		responseError := parseResponseError(res)
-		if responseError != nil {
-			c.logger.Error(
-				"response error",
-				slog.String("url", uri),
-				slog.Int("status", responseError.StatusCode),
-				slog.String("error message", responseError.ErrorMessage),
-			)
-		} else {
-			c.logger.Error(
-				"response error",
-				slog.String("url", uri),
-				slog.Int("status", res.StatusCode()),
-				slog.String("error message", res.Status()),
-				slog.String("response", string(res.Body())),
-			)
+		c.logResponseError(responseError)
+		return res, err
+	}
+
+	if err := c.decode(res, response); err != nil {
+		return res, err
+	}
+	if cacheable && options.CacheControl != model.CacheControlBypass {
+		c.cache.Set(key, res.Body(), ttl)
+	}
+	return res, nil
+}
+
+// cachePolicy returns the cache key and TTL of a request. It returns false if the response must not be cached.
+func (c *Client) cachePolicy(method, endpoint, uri string, options *model.RequestOptions) (string, time.Duration, bool) {
+	if c.cache == nil || method != http.MethodGet {
+		return "", 0, false
+	}
+	ttl, ok := c.cacheTTLs[endpoint]
+	if !ok || ttl <= 0 {
+		return "", 0, false
+	}
+	return cacheKey(method, uri, options.QueryParams), ttl, true
+}
+
+// cachedResponse wraps a cached body the way resty would and unmarshals it into the response.
+func (c *Client) cachedResponse(ctx context.Context, body []byte, response any) (*resty.Response, error) {
+	if response != nil {
+		if err := json.Unmarshal(body, response); err != nil {
+			return nil, fmt.Errorf("unmarshaling cached response: %w", err)
 		}
-		return res, fmt.Errorf("service responded with an unexpected error code: %w", responseError)
 	}
+	res := &resty.Response{
+		Request: c.HTTP.R().SetContext(ctx),
+		RawResponse: &http.Response{
+			Status:     http.StatusText(http.StatusOK),
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+		},
+	}
+	return res.SetBody(body), nil
+}
 
-	if options.Trace {
-		sanitizedHeaders := req.Header
-		for k := range sanitizedHeaders {
-			if k == "Authorization" {
-				sanitizedHeaders[k] = []string{"REDACTED"}
+// decode unmarshals the response body into the response unless the response is nil.
+func (c *Client) decode(res *resty.Response, response any) error {
+	if response == nil {
+		return nil
+	}
+	if err := json.Unmarshal(res.Body(), response); err != nil {
+		return fmt.Errorf("unmarshaling response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) responseError(endpoint string, res *resty.Response) *fmperrors.ResponseError {
+	responseError := fmperrors.NewResponseError(res.StatusCode(), res.Body(), endpoint, res.Request.URL)
+	c.logResponseError(responseError)
+	return responseError
+}
+
+func (c *Client) logResponseError(responseError *fmperrors.ResponseError) {
+	c.logger.Error(
+		"response error",
+		slog.String("url", responseError.URL),
+		slog.Int("status", responseError.StatusCode),
+		slog.String("error message", responseError.ErrorMessage),
+	)
+}
+
+// execute sends the request through the middlewares and retries it according to the retry policy of the client.
+// Every attempt is subject to the rate limiter.
+func (c *Client) execute(ctx context.Context, method, endpoint, uri string, options *model.RequestOptions) (*resty.Response, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("rate limiting request: %w", err)
 			}
 		}
+		req, err := c.newRequest(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		res, err := c.roundTrip(ctx, &Request{
+			Method:   method,
+			Endpoint: endpoint,
+			URI:      uri,
+			Attempt:  attempt,
+			Options:  options,
+			HTTP:     req,
+		})
+		if err == nil || !c.retryPolicy.shouldRetry(method, err) {
+			return res, err
+		}
+		wait, ok := c.retryPolicy.backoff(attempt+1, time.Since(start), res)
+		if !ok {
+			return res, err
+		}
+		if c.retryPolicy.OnRetry != nil {
+			c.retryPolicy.OnRetry(attempt+1, err, wait)
+		}
 		c.logger.Debug(
-			"request",
+			"retrying request",
 			slog.String("url", uri),
-			slog.Any("request headers", sanitizedHeaders),
-			slog.Any("response headers", res.Header()),
+			slog.Int("attempt", attempt+1),
+			slog.Duration("wait", wait),
+			slog.Any("error", err),
 		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, ctx.Err()
+		case <-timer.C:
+		}
 	}
-	return res, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, options *model.RequestOptions) (*resty.Request, error) {
+	req := c.HTTP.R().SetContext(ctx)
+	if options.Body != nil {
+		b, err := json.Marshal(options.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal body: %w", err)
+		}
+		req.SetBody(b)
+	}
+	req.SetQueryParamsFromValues(options.QueryParams)
+	req.SetHeaderMultiValues(options.Headers)
+	req.SetHeader("Content-Type", options.ContentType)
+	req.SetDoNotParseResponse(options.Stream)
+	return req, nil
 }
 
 func mergeOptions(opts ...model.RequestOption) *model.RequestOptions {
@@ -132,13 +376,34 @@ func mergeOptions(opts ...model.RequestOption) *model.RequestOptions {
 	return options
 }
 
-func parseResponseError(res *resty.Response) *model.ResponseError {
-	if res == nil {
-		return nil
-	}
-	responseError := res.Error().(*model.ResponseError)
-	responseError.StatusCode = res.StatusCode()
-	responseError.ErrorMessage = res.String()
+// streamedBody is the body of a streamed response. Closing it records the telemetry of the call with the number of
+// bytes read and releases the timeout of the call.
+type streamedBody struct {
+	io.ReadCloser
+	cancel  context.CancelFunc
+	onClose func(size int64)
 
-	return responseError
+	size      int64
+	closeOnce sync.Once
+}
+
+func (b *streamedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	return n, err
+}
+
+func (b *streamedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.closeOnce.Do(func() {
+		b.onClose(b.size)
+		b.cancel()
+	})
+	return err
+}
+
+// endpointPath strips the query from a request URI.
+func endpointPath(uri string) string {
+	path, _, _ := strings.Cut(uri, "?")
+	return path
 }
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func TestClient_CallResponseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       error
+	}{
+		{
+			name:       "premium-endpoint",
+			statusCode: http.StatusPaymentRequired,
+			body:       `Restricted Endpoint: This endpoint is not available under your current subscription`,
+			want:       fmperrors.ErrPremiumEndpoint,
+		},
+		{
+			name:       "error-message-with-success-status",
+			statusCode: http.StatusOK,
+			body:       `{"Error Message": "Invalid API KEY. Feel free to create a Free API Key."}`,
+			want:       fmperrors.ErrUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			c := newTestClient(t, srv.URL)
+
+			var res []any
+			_, err := c.Call(context.Background(), http.MethodGet, "/stable/historical-chart/:timeframe", struct {
+				Timeframe string `path:"timeframe"`
+				Symbol    string `query:"symbol"`
+			}{Timeframe: "1min", Symbol: "AAPL"}, &res)
+			require.ErrorIs(t, err, tt.want)
+
+			responseError, ok := fmperrors.AsResponseError(err)
+			require.True(t, ok)
+			assert.Equal(t, "/stable/historical-chart/:timeframe", responseError.Path)
+			assert.Contains(t, responseError.URL, "/stable/historical-chart/1min?")
+			assert.Contains(t, responseError.URL, "apikey=REDACTED")
+			assert.NotContains(t, responseError.URL, "test-api-key")
+		})
+	}
+}
+
+func TestClient_Open(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch r.URL.Query().Get("part") {
+		case "0":
+			if attempts == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"Error Message": "Limit Reach . Please upgrade your plan"}`))
+				return
+			}
+			_, _ = w.Write([]byte("symbol,price\nAAPL,243.85\n"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"Error Message": "No more data"}`))
+		}
+	}))
+	defer srv.Close()
+
+	type partParams struct {
+		Part int `query:"part"`
+	}
+	c := newTestClient(t, srv.URL, WithRetryPolicy(RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}))
+	ctx := context.Background()
+
+	t.Run("success:streamed body after retry", func(t *testing.T) {
+		res, body, err := c.Open(ctx, http.MethodGet, "/stable/profile-bulk", partParams{Part: 0})
+		require.NoError(t, err)
+		defer body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode())
+		assert.Empty(t, res.Body(), "the body must not be read by the client")
+
+		b, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, "symbol,price\nAAPL,243.85\n", string(b))
+		assert.Equal(t, 2, attempts)
+	})
+	t.Run("success:ignored error status code", func(t *testing.T) {
+		res, body, err := c.Open(ctx, http.MethodGet, "/stable/profile-bulk", partParams{Part: 1}, model.WithIgnoredErrorStatusCodes(http.StatusBadRequest))
+		require.NoError(t, err)
+		defer body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode())
+	})
+	t.Run("error:response error", func(t *testing.T) {
+		_, _, err := c.Open(ctx, http.MethodGet, "/stable/profile-bulk", partParams{Part: 1})
+		responseError, ok := fmperrors.AsResponseError(err)
+		require.True(t, ok)
+		assert.Equal(t, "No more data", responseError.ErrorMessage)
+	})
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+// flightGroup coalesces identical calls that are in flight at the same time into a single request.
+//
+// Unlike golang.org/x/sync/singleflight, the shared request is detached from the context of the caller
+// that started it. Every caller waits on its own context and the shared request is only cancelled once
+// all of them have given up.
+type flightGroup struct {
+	lock    sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done    chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+
+	res *resty.Response
+	err error
+}
+
+type flightFunc func(ctx context.Context) (*resty.Response, error)
+
+// do runs fn once for all concurrent callers with the same key and returns its result to each of them.
+func (g *flightGroup) do(ctx context.Context, key string, fn flightFunc) (*resty.Response, error) {
+	g.lock.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f, ok := g.flights[key]
+	if !ok {
+		f = g.start(ctx, key, fn)
+	}
+	f.waiters++
+	g.lock.Unlock()
+
+	select {
+	case <-f.done:
+		return f.res, f.err
+	case <-ctx.Done():
+		g.lock.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forget(key, f)
+		}
+		g.lock.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// start runs fn in the background. It must be called with the lock held.
+func (g *flightGroup) start(ctx context.Context, key string, fn flightFunc) *flight {
+	flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	f := &flight{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	g.flights[key] = f
+
+	go func() {
+		defer cancel()
+		f.res, f.err = fn(flightCtx)
+
+		g.lock.Lock()
+		g.forget(key, f)
+		g.lock.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+// forget removes the flight so that later callers start a new one. It must be called with the lock held.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// coalescable reports whether identical calls with the given options may share a single request.
+func coalescable(method string, options *model.RequestOptions) bool {
+	return method == http.MethodGet && options.Body == nil && len(options.Headers) == 0
+}
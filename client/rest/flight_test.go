@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testQuote struct {
+	Symbol string `json:"symbol"`
+}
+
+func newSlowServer(t *testing.T, delay time.Duration, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"symbol": "AAPL"}]`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_CallCoalescing(t *testing.T) {
+	var calls atomic.Int32
+	srv := newSlowServer(t, 100*time.Millisecond, &calls)
+	c := newTestClient(t, srv.URL)
+
+	const waiters = 5
+	var wg sync.WaitGroup
+	results := make([][]testQuote, waiters)
+	errs := make([]error, waiters)
+	for i := range waiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.CallURL(context.Background(), http.MethodGet, "/stable/quote?symbol=AAPL", &results[i])
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for i := range waiters {
+		require.NoError(t, errs[i])
+		require.Len(t, results[i], 1)
+		assert.Equal(t, "AAPL", results[i][0].Symbol)
+	}
+}
+
+func TestClient_CallCoalescingCancel(t *testing.T) {
+	var calls atomic.Int32
+	srv := newSlowServer(t, 100*time.Millisecond, &calls)
+	c := newTestClient(t, srv.URL)
+
+	cancelledCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var cancelledErr, err error
+	var res []testQuote
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, cancelledErr = c.CallURL(cancelledCtx, http.MethodGet, "/stable/quote?symbol=AAPL", nil)
+	}()
+	go func() {
+		defer wg.Done()
+		time.Sleep(5 * time.Millisecond)
+		_, err = c.CallURL(context.Background(), http.MethodGet, "/stable/quote?symbol=AAPL", &res)
+	}()
+	wg.Wait()
+
+	require.ErrorIs(t, cancelledErr, context.DeadlineExceeded)
+	require.NoError(t, err, "cancelling one waiter must not cancel the shared call")
+	require.Len(t, res, 1)
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,17 @@
+package rest
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func newTestClient(t *testing.T, baseURL string, opts ...Option) *Client {
+	t.Helper()
+
+	return New(
+		"test-api-key",
+		slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		append([]Option{WithBaseURL(baseURL)}, opts...)...,
+	)
+}
@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Plan defines an FMP subscription plan tier.
+type Plan string
+
+const (
+	PlanBasic    Plan = "basic"
+	PlanStarter  Plan = "starter"
+	PlanPremium  Plan = "premium"
+	PlanUltimate Plan = "ultimate"
+)
+
+// Budget defines the number of calls allowed per window. A zero value means the window is not limited.
+//
+// As returned by RateLimiter.Remaining, it holds the number of calls left per window instead, and the windows that
+// are not limited are reported as -1, so that they are not mistaken for exhausted ones.
+type Budget struct {
+	PerMinute int
+	PerDay    int
+}
+
+// Budget returns the published call budget of the plan.
+func (p Plan) Budget() (Budget, error) {
+	switch p {
+	case PlanBasic:
+		return Budget{PerDay: 250}, nil
+	case PlanStarter:
+		return Budget{PerMinute: 300}, nil
+	case PlanPremium:
+		return Budget{PerMinute: 750}, nil
+	case PlanUltimate:
+		return Budget{PerMinute: 3000}, nil
+	}
+	return Budget{}, fmt.Errorf("unknown plan: %q", p)
+}
+
+// RateLimiter throttles outgoing calls. Wait must block until a call is allowed or the context is done.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+	Remaining() Budget
+}
+
+// TokenBucketLimiter is a RateLimiter with a token bucket per budget window.
+type TokenBucketLimiter struct {
+	perMinute *rate.Limiter
+	perDay    *rate.Limiter
+}
+
+// NewTokenBucketLimiter returns a limiter that enforces the given budget.
+func NewTokenBucketLimiter(budget Budget) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		perMinute: newWindowLimiter(budget.PerMinute, time.Minute),
+		perDay:    newWindowLimiter(budget.PerDay, 24*time.Hour),
+	}
+}
+
+// NewPlanLimiter returns a limiter that enforces the budget of the given plan.
+func NewPlanLimiter(plan Plan) (*TokenBucketLimiter, error) {
+	budget, err := plan.Budget()
+	if err != nil {
+		return nil, err
+	}
+	return NewTokenBucketLimiter(budget), nil
+}
+
+// Wait blocks until both the per-minute and the per-day budget allow a call. The call is reserved in both budgets
+// at once, and the reservations are cancelled if the context is done first, so that a call that is not made does
+// not use up any budget.
+func (l *TokenBucketLimiter) Wait(ctx context.Context) error {
+	now := time.Now()
+	var (
+		reservations []*rate.Reservation
+		delay        time.Duration
+	)
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	for _, limiter := range []*rate.Limiter{l.perMinute, l.perDay} {
+		if limiter == nil {
+			continue
+		}
+		r := limiter.ReserveN(now, 1)
+		if !r.OK() {
+			cancel()
+			return errors.New("waiting for budget: the budget does not allow a single call")
+		}
+		reservations = append(reservations, r)
+		delay = max(delay, r.DelayFrom(now))
+	}
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		cancel()
+		return fmt.Errorf("waiting for budget: %w", context.DeadlineExceeded)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("waiting for budget: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Remaining returns the number of calls that can be made right now without blocking.
+// Windows that are not limited are reported as -1.
+func (l *TokenBucketLimiter) Remaining() Budget {
+	return Budget{
+		PerMinute: remainingTokens(l.perMinute),
+		PerDay:    remainingTokens(l.perDay),
+	}
+}
+
+func newWindowLimiter(calls int, window time.Duration) *rate.Limiter {
+	if calls <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(float64(calls)/window.Seconds()), calls)
+}
+
+func remainingTokens(l *rate.Limiter) int {
+	if l == nil {
+		return -1
+	}
+	return max(int(math.Floor(l.Tokens())), 0)
+}
@@ -0,0 +1,68 @@
+package rest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan_Budget(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    Plan
+		want    Budget
+		wantErr bool
+	}{
+		{name: "success:basic", plan: PlanBasic, want: Budget{PerDay: 250}},
+		{name: "success:starter", plan: PlanStarter, want: Budget{PerMinute: 300}},
+		{name: "success:premium", plan: PlanPremium, want: Budget{PerMinute: 750}},
+		{name: "success:ultimate", plan: PlanUltimate, want: Budget{PerMinute: 3000}},
+		{name: "error:unknown", plan: Plan("enterprise"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan.Budget()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	limiter := NewTokenBucketLimiter(Budget{PerMinute: 2})
+	ctx := context.Background()
+
+	assert.Equal(t, Budget{PerMinute: 2, PerDay: -1}, limiter.Remaining())
+	require.NoError(t, limiter.Wait(ctx))
+	require.NoError(t, limiter.Wait(ctx))
+	assert.Equal(t, Budget{PerMinute: 0, PerDay: -1}, limiter.Remaining())
+
+	// The budget is exhausted so the next call must block until the context expires.
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, limiter.Wait(ctx))
+}
+
+func TestTokenBucketLimiter_WaitCancelled(t *testing.T) {
+	limiter := NewTokenBucketLimiter(Budget{PerMinute: 1, PerDay: 10})
+	require.NoError(t, limiter.Wait(context.Background()))
+	assert.Equal(t, Budget{PerMinute: 0, PerDay: 9}, limiter.Remaining())
+
+	// Calls that are not made do not use up the daily budget while waiting for the minute budget.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+	assert.Equal(t, Budget{PerMinute: 0, PerDay: 9}, limiter.Remaining())
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	require.ErrorIs(t, limiter.Wait(ctx), context.Canceled)
+	assert.Equal(t, Budget{PerMinute: 0, PerDay: 9}, limiter.Remaining())
+}
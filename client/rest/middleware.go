@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+	"go.tradeforge.dev/fmp/model"
+)
+
+// Request describes a single attempt of an API call as seen by middlewares.
+type Request struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// Endpoint is the path the request URI was built from, e.g. /stable/historical-chart/:timeframe.
+	Endpoint string
+
+	// URI is the request URI with the encoded path and query params.
+	URI string
+
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int
+
+	// Options are the options of the call.
+	Options *model.RequestOptions
+
+	// HTTP is the underlying request. Middlewares may change it, e.g. to override headers.
+	HTTP *resty.Request
+}
+
+// Params returns the encoded query params of the request, including the ones set through the request options.
+func (r *Request) Params() url.Values {
+	_, rawQuery, _ := strings.Cut(r.URI, "?")
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		params = url.Values{}
+	}
+	for k, vs := range r.Options.QueryParams {
+		for _, v := range vs {
+			params.Add(k, v)
+		}
+	}
+	return params
+}
+
+// RoundTrip sends a single attempt of an API call. Responses with an error status code, or with an FMP error message
+// and a successful status code, are returned together with a *errors.ResponseError.
+type RoundTrip func(ctx context.Context, req *Request) (*resty.Response, error)
+
+// Middleware wraps a RoundTrip with cross-cutting behavior such as logging, metrics or fault injection.
+type Middleware func(next RoundTrip) RoundTrip
+
+// WithMiddleware adds middlewares to the client. The first middleware is the outermost one and sees the request
+// first and the response last.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// TraceMiddleware logs the headers of requests made with the model.WithTrace option.
+func TraceMiddleware(logger *slog.Logger) Middleware {
+	return func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, req *Request) (*resty.Response, error) {
+			res, err := next(ctx, req)
+			if !req.Options.Trace || err != nil {
+				return res, err
+			}
+			sanitizedHeaders := req.HTTP.Header.Clone()
+			for k := range sanitizedHeaders {
+				if k == "Authorization" {
+					sanitizedHeaders[k] = []string{"REDACTED"}
+				}
+			}
+			logger.Debug(
+				"request",
+				slog.String("url", req.URI),
+				slog.Int("attempt", req.Attempt),
+				slog.Any("request headers", sanitizedHeaders),
+				slog.Any("response headers", res.Header()),
+			)
+			return res, nil
+		}
+	}
+}
+
+// chain wraps the round trip with the middlewares.
+func chain(roundTrip RoundTrip, middlewares ...Middleware) RoundTrip {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		roundTrip = middlewares[i](roundTrip)
+	}
+	return roundTrip
+}
+
+// roundTrip is the innermost RoundTrip that actually sends the request.
+func roundTrip(_ context.Context, req *Request) (*resty.Response, error) {
+	res, err := req.HTTP.Execute(req.Method, req.URI)
+	if err != nil {
+		return res, err
+	}
+	if res.IsError() {
+		if req.Options.Stream {
+			// Error responses are small and read in full, so that they can be reported like any other.
+			if err := readRawBody(res); err != nil {
+				return res, err
+			}
+		}
+		return res, fmperrors.NewResponseError(res.StatusCode(), res.Body(), req.Endpoint, res.Request.URL)
+	}
+	// FMP may respond with an error message and a successful status code, e.g. once the rate limit is reached.
+	// Streamed bodies are checked as they are read instead.
+	if !req.Options.Stream && fmperrors.HasErrorMessage(res.Body()) {
+		return res, fmperrors.NewResponseError(res.StatusCode(), res.Body(), req.Endpoint, res.Request.URL)
+	}
+	return res, nil
+}
+
+// maxErrorBodySize is the maximum number of bytes read from the body of a streamed error response.
+const maxErrorBodySize = 64 << 10
+
+// readRawBody reads the unparsed body of the response into its body.
+func readRawBody(res *resty.Response) error {
+	body := res.RawBody()
+	defer body.Close()
+	b, err := io.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	if err != nil {
+		return fmt.Errorf("reading error response: %w", err)
+	}
+	res.SetBody(b)
+	return nil
+}
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+	"go.tradeforge.dev/fmp/model"
+)
+
+type recordedRoundTrip struct {
+	endpoint string
+	symbol   string
+	short    string
+	attempt  int
+	status   int
+	err      error
+}
+
+func TestClient_CallMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "override", r.Header.Get("X-Test"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	var recorded []recordedRoundTrip
+	recorder := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, req *Request) (*resty.Response, error) {
+			res, err := next(ctx, req)
+			params := req.Params()
+			recorded = append(recorded, recordedRoundTrip{
+				endpoint: req.Endpoint,
+				symbol:   params.Get("symbol"),
+				short:    params.Get("short"),
+				attempt:  req.Attempt,
+				status:   res.StatusCode(),
+				err:      err,
+			})
+			return res, err
+		}
+	}
+	// Fails the first attempt with a network error before it reaches the server.
+	faultInjector := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, req *Request) (*resty.Response, error) {
+			if req.Attempt == 1 {
+				return &resty.Response{}, &net.OpError{Op: "dial", Err: assert.AnError}
+			}
+			req.HTTP.SetHeader("X-Test", "override")
+			return next(ctx, req)
+		}
+	}
+
+	c := newTestClient(t, srv.URL,
+		WithRetryPolicy(testRetryPolicy(nil)),
+		WithMiddleware(recorder, faultInjector),
+	)
+	_, err := c.Call(context.Background(), http.MethodGet, "/stable/quote", struct {
+		Symbol string `query:"symbol"`
+	}{Symbol: "AAPL"}, nil, model.QueryParam("short", "false"))
+	require.ErrorIs(t, err, fmperrors.ErrNotFound)
+
+	require.Len(t, recorded, 2)
+	assert.Equal(t, 1, recorded[0].attempt)
+	assert.Error(t, recorded[0].err)
+	assert.Equal(t, 2, recorded[1].attempt)
+	assert.Equal(t, "/stable/quote", recorded[1].endpoint)
+	assert.Equal(t, "AAPL", recorded[1].symbol)
+	assert.Equal(t, "false", recorded[1].short)
+	assert.Equal(t, http.StatusNotFound, recorded[1].status)
+	assert.ErrorIs(t, recorded[1].err, fmperrors.ErrNotFound)
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	middleware := func(name string) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, req *Request) (*resty.Response, error) {
+				order = append(order, name)
+				return next(ctx, req)
+			}
+		}
+	}
+	rt := chain(func(context.Context, *Request) (*resty.Response, error) {
+		order = append(order, "transport")
+		return nil, nil
+	}, middleware("outer"), middleware("inner"))
+
+	_, err := rt(context.Background(), &Request{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner", "transport"}, order)
+}
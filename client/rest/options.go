@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+)
+
+// httpConfig holds the options used to build the underlying HTTP client.
+type httpConfig struct {
+	baseURL    string
+	userAgent  string
+	timeout    time.Duration
+	proxyURL   string
+	httpClient *http.Client
+	transport  http.RoundTripper
+}
+
+// WithBaseURL points the client at another server, e.g. a local stand-in of the FMP API.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.httpConfig.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient makes the client send requests with the given HTTP client.
+// Its timeout is kept unless WithTimeout is used as well. The client is copied and never modified.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpConfig.httpClient = httpClient
+	}
+}
+
+// WithTransport makes the client send requests through the given transport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpConfig.transport = transport
+	}
+}
+
+// WithTimeout sets the timeout of every attempt of a call. It defaults to DefaultClientTimeout.
+// Use model.WithTimeout to limit a single call instead.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpConfig.timeout = timeout
+	}
+}
+
+// WithUserAgent replaces the default User-Agent header.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.httpConfig.userAgent = userAgent
+	}
+}
+
+// WithProxy sends requests through the proxy at the given URL.
+func WithProxy(proxyURL string) Option {
+	return func(c *Client) {
+		c.httpConfig.proxyURL = proxyURL
+	}
+}
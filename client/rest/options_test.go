@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+type countingTransport struct {
+	calls atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClient_Options(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		assert.Equal(t, "test-api-key", r.URL.Query().Get("apikey"))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	transport := &countingTransport{}
+	c := newTestClient(t, srv.URL, WithTransport(transport), WithUserAgent("test-agent"))
+
+	var res []any
+	_, err := c.CallURL(context.Background(), http.MethodGet, "/stable/quote", &res)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), transport.calls.Load())
+}
+
+func TestClient_HTTPClientNotModified(t *testing.T) {
+	transport := &http.Transport{}
+	httpClient := &http.Client{Transport: transport, Timeout: time.Minute}
+	newTestClient(t, "http://localhost", WithHTTPClient(httpClient), WithTimeout(time.Second), WithProxy("http://proxy.local:8080"))
+	assert.Same(t, transport, httpClient.Transport)
+	assert.Equal(t, time.Minute, httpClient.Timeout)
+	assert.Nil(t, transport.Proxy)
+
+	httpClient = &http.Client{}
+	newTestClient(t, "http://localhost", WithHTTPClient(httpClient), WithTimeout(time.Second))
+	assert.Nil(t, httpClient.Transport)
+	assert.Zero(t, httpClient.Timeout)
+}
+
+func TestClient_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	t.Run("per-call", func(t *testing.T) {
+		c := newTestClient(t, srv.URL)
+		_, err := c.CallURL(context.Background(), http.MethodGet, "/stable/quote", nil, model.WithTimeout(20*time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// The timeout of a single call must not leak into the following ones.
+		_, err = c.CallURL(context.Background(), http.MethodGet, "/stable/quote", nil)
+		require.NoError(t, err)
+	})
+	t.Run("client", func(t *testing.T) {
+		policy := DefaultRetryPolicy()
+		policy.MaxRetries = 0
+		c := newTestClient(t, srv.URL, WithTimeout(20*time.Millisecond), WithRetryPolicy(policy))
+		_, err := c.CallURL(context.Background(), http.MethodGet, "/stable/quote", nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+)
+
+const (
+	DefaultRetryInitialInterval = 500 * time.Millisecond
+	DefaultRetryMaxInterval     = 30 * time.Second
+	DefaultRetryMaxElapsedTime  = 2 * time.Minute
+	DefaultRetryMultiplier      = 2.0
+	DefaultRetryJitter          = 0.5
+)
+
+// retryableStatusCodes are the status codes FMP responds with on transient failures.
+var retryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy defines how failed calls are retried. Only idempotent requests are retried and only when they fail
+// on a network error, a transient status code (429, 502, 503 and 504) or a rate limit error message, which FMP may
+// send with a successful status code. Permanent failures are returned right away.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+
+	// InitialInterval is the backoff before the first retry.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the backoff between two attempts.
+	MaxInterval time.Duration
+
+	// Multiplier grows the backoff after each retry.
+	Multiplier float64
+
+	// Jitter randomizes the backoff by the given factor, e.g. 0.5 yields a backoff between 50% and 150% of the interval.
+	Jitter float64
+
+	// MaxElapsedTime stops retrying once the call has taken longer than that. Zero means no limit.
+	MaxElapsedTime time.Duration
+
+	// OnRetry is called before each retry with the number of the upcoming attempt (starting at 2),
+	// the error of the previous attempt and the time to wait.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// DefaultRetryPolicy returns the retry policy used by the client unless configured otherwise.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:      DefaultRetryCount,
+		InitialInterval: DefaultRetryInitialInterval,
+		MaxInterval:     DefaultRetryMaxInterval,
+		Multiplier:      DefaultRetryMultiplier,
+		Jitter:          DefaultRetryJitter,
+		MaxElapsedTime:  DefaultRetryMaxElapsedTime,
+	}
+}
+
+// WithRetryPolicy replaces the default retry policy of the client.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// shouldRetry reports whether an attempt that failed with the given error may be retried.
+func (p RetryPolicy) shouldRetry(method string, err error) bool {
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	var responseError *fmperrors.ResponseError
+	if errors.As(err, &responseError) {
+		return slices.Contains(retryableStatusCodes, responseError.StatusCode) || errors.Is(err, fmperrors.ErrRateLimited)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoff returns the time to wait before the given attempt. It returns false if no more attempts should be made.
+func (p RetryPolicy) backoff(attempt int, elapsed time.Duration, res *resty.Response) (time.Duration, bool) {
+	if attempt > p.MaxRetries+1 {
+		return 0, false
+	}
+
+	interval := float64(p.InitialInterval)
+	for range attempt - 2 {
+		interval *= p.Multiplier
+	}
+	if p.MaxInterval > 0 {
+		interval = min(interval, float64(p.MaxInterval))
+	}
+	if p.Jitter > 0 {
+		delta := p.Jitter * interval
+		interval = interval - delta + rand.Float64()*2*delta //nolint:gosec // jitter does not need a secure source
+	}
+	wait := time.Duration(interval)
+	if retryAfter, ok := parseRetryAfter(res); ok {
+		wait = max(wait, retryAfter)
+	}
+
+	if p.MaxElapsedTime > 0 && elapsed+wait > p.MaxElapsedTime {
+		return 0, false
+	}
+	return wait, true
+}
+
+// parseRetryAfter parses the Retry-After header which holds either a number of seconds or an HTTP date.
+func parseRetryAfter(res *resty.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	v := res.Header().Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+)
+
+func testRetryPolicy(onRetry func(attempt int, err error, wait time.Duration)) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:      3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     10 * time.Millisecond,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxElapsedTime:  time.Second,
+		OnRetry:         onRetry,
+	}
+}
+
+func TestClient_CallRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statusCodes  []int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{
+			name:         "success:retry-transient-errors",
+			method:       http.MethodGet,
+			statusCodes:  []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK},
+			wantAttempts: 3,
+		},
+		{
+			name:         "error:retries-exhausted",
+			method:       http.MethodGet,
+			statusCodes:  []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantAttempts: 4,
+			wantErr:      true,
+		},
+		{
+			name:         "error:permanent-failure",
+			method:       http.MethodGet,
+			statusCodes:  []int{http.StatusBadRequest, http.StatusOK},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "error:non-idempotent-method",
+			method:       http.MethodPost,
+			statusCodes:  []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := attempts.Add(1)
+				w.WriteHeader(tt.statusCodes[n-1])
+				_, _ = w.Write([]byte(`[]`))
+			}))
+			defer srv.Close()
+
+			var retries []int
+			c := newTestClient(t, srv.URL, WithRetryPolicy(testRetryPolicy(func(attempt int, _ error, _ time.Duration) {
+				retries = append(retries, attempt)
+			})))
+
+			var res []any
+			_, err := c.CallURL(context.Background(), tt.method, "/stable/quote", &res)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantAttempts, attempts.Load())
+			assert.Len(t, retries, int(tt.wantAttempts)-1)
+			for i, attempt := range retries {
+				assert.Equal(t, i+2, attempt)
+			}
+		})
+	}
+}
+
+func TestClient_CallRetryRateLimitMessage(t *testing.T) {
+	// FMP reports a reached rate limit with a successful status code as well.
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"Error Message": "Limit Reach . Please upgrade your plan"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"symbol": "AAPL"}]`))
+	}))
+	defer srv.Close()
+
+	var retryErr error
+	c := newTestClient(t, srv.URL, WithRetryPolicy(testRetryPolicy(func(_ int, err error, _ time.Duration) {
+		retryErr = err
+	})))
+
+	var res []map[string]any
+	_, err := c.CallURL(context.Background(), http.MethodGet, "/stable/quote", &res)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"symbol": "AAPL"}}, res)
+	assert.Equal(t, int32(2), attempts.Load())
+	require.ErrorIs(t, retryErr, fmperrors.ErrRateLimited)
+}
+
+func TestClient_CallRetryAfter(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	policy := testRetryPolicy(func(_ int, _ error, wait time.Duration) {
+		waits = append(waits, wait)
+	})
+	policy.MaxElapsedTime = 5 * time.Second
+	c := newTestClient(t, srv.URL, WithRetryPolicy(policy))
+
+	var res []any
+	_, err := c.CallURL(context.Background(), http.MethodGet, "/stable/quote", &res)
+	require.NoError(t, err)
+	require.Len(t, waits, 1)
+	assert.GreaterOrEqual(t, waits[0], time.Second)
+}
+
+func TestClient_CallRetryContextCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	policy := testRetryPolicy(nil)
+	policy.MaxElapsedTime = 0
+	c := newTestClient(t, srv.URL, WithRetryPolicy(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.CallURL(ctx, http.MethodGet, "/stable/quote", nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+	"go.tradeforge.dev/fmp/model"
+)
+
+// Stream makes an API call whose response is a JSON array and returns an iterator over its elements.
+//
+// The elements are decoded one at a time as the body arrives, so memory stays flat regardless of the size of the
+// response. The call is made when iteration starts and the body is closed when it ends. Iteration stops after the
+// first error, which is yielded. An empty body, e.g. the one of a response with an ignored error status code, has no
+// elements.
+func Stream[T any](ctx context.Context, c *Client, method, path string, params any, opts ...model.RequestOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		res, body, err := c.Open(ctx, method, path, params, opts...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer body.Close()
+
+		r := bufio.NewReader(body)
+		if isObject(r) {
+			// FMP may respond with an error message and a successful status code.
+			b, err := io.ReadAll(io.LimitReader(r, maxErrorBodySize))
+			if err == nil && fmperrors.HasErrorMessage(b) {
+				err = c.responseError(path, res.SetBody(b))
+			} else if err == nil {
+				err = fmt.Errorf("unexpected JSON object instead of array: %.100s", b)
+			}
+			yield(zero, err)
+			return
+		}
+
+		if _, err := r.Peek(1); errors.Is(err, io.EOF) {
+			return
+		}
+
+		decoder := json.NewDecoder(r)
+		if err := expectDelim(decoder, '['); err != nil {
+			yield(zero, err)
+			return
+		}
+		for decoder.More() {
+			var v T
+			if err := decoder.Decode(&v); err != nil {
+				yield(zero, fmt.Errorf("decoding element: %w", err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := expectDelim(decoder, ']'); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// isObject reports whether the JSON value of the reader is an object, without consuming it.
+func isObject(r *bufio.Reader) bool {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = r.ReadByte()
+		default:
+			return b[0] == '{'
+		}
+	}
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("reading %v: %w", delim, err)
+	}
+	if token != delim {
+		return fmt.Errorf("unexpected token %v, expected %v", token, delim)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func TestStream(t *testing.T) {
+	type quote struct {
+		Symbol string  `json:"symbol"`
+		Price  float64 `json:"price"`
+	}
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		opts       []model.RequestOption
+		take       int
+		want       []quote
+		wantErr    error
+		wantErrMsg string
+	}{
+		{
+			name:       "success:elements",
+			statusCode: http.StatusOK,
+			body:       ` [{"symbol":"AAPL","price":243.85},{"symbol":"MSFT","price":418.58}]`,
+			want:       []quote{{Symbol: "AAPL", Price: 243.85}, {Symbol: "MSFT", Price: 418.58}},
+		},
+		{
+			name:       "success:empty array",
+			statusCode: http.StatusOK,
+			body:       `[]`,
+		},
+		{
+			name:       "success:ignored error status",
+			statusCode: http.StatusNotFound,
+			body:       `{"Error Message": "Not found"}`,
+			opts:       []model.RequestOption{model.WithIgnoredErrorStatusCodes(http.StatusNotFound)},
+		},
+		{
+			name:       "success:early break",
+			statusCode: http.StatusOK,
+			body:       `[{"symbol":"AAPL"},{"symbol":"MSFT"},{"symbol":"NVDA"}]`,
+			take:       1,
+			want:       []quote{{Symbol: "AAPL"}},
+		},
+		{
+			name:       "error:truncated body",
+			statusCode: http.StatusOK,
+			body:       `[{"symbol":"AAPL"},{"symbol":`,
+			want:       []quote{{Symbol: "AAPL"}},
+			wantErrMsg: "decoding element",
+		},
+		{
+			name:       "error:error message with success status",
+			statusCode: http.StatusOK,
+			body:       `{"Error Message": "Invalid API KEY. Feel free to create a Free API Key."}`,
+			wantErr:    fmperrors.ErrUnauthorized,
+		},
+		{
+			name:       "error:error status",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"Error Message": "Limit Reach . Please upgrade your plan"}`,
+			wantErr:    fmperrors.ErrRateLimited,
+		},
+		{
+			name:       "error:not an array",
+			statusCode: http.StatusOK,
+			body:       `"quotes"`,
+			wantErrMsg: "unexpected token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			c := newTestClient(t, srv.URL, WithRetryPolicy(RetryPolicy{}))
+
+			var got []quote
+			var err error
+			for q, e := range Stream[quote](context.Background(), c, http.MethodGet, "/stable/batch-exchange-quote", nil, tt.opts...) {
+				if e != nil {
+					err = e
+					break
+				}
+				got = append(got, q)
+				if len(got) == tt.take {
+					break
+				}
+			}
+			assert.Equal(t, tt.want, got)
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			case tt.wantErrMsg != "":
+				require.ErrorContains(t, err, tt.wantErrMsg)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	fmperrors "go.tradeforge.dev/fmp/errors"
+)
+
+const instrumentationName = "go.tradeforge.dev/fmp/client/rest"
+
+const (
+	attrEndpoint      = attribute.Key("fmp.endpoint")
+	attrMethod        = attribute.Key("http.request.method")
+	attrStatusCode    = attribute.Key("http.response.status_code")
+	attrRetryCount    = attribute.Key("fmp.retry_count")
+	attrCacheHit      = attribute.Key("fmp.cache_hit")
+	attrErrorCategory = attribute.Key("fmp.error.category")
+)
+
+// WithTracerProvider records a span for every call made by the client.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *Client) {
+		c.tracer = provider.Tracer(instrumentationName, trace.WithInstrumentationVersion(clientVersion))
+	}
+}
+
+// WithMeterProvider records latency, response size and error metrics for every call made by the client.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *Client) {
+		m, err := newClientMetrics(provider.Meter(instrumentationName, metric.WithInstrumentationVersion(clientVersion)))
+		if err != nil {
+			c.logger.Warn("metrics disabled", slog.Any("error", err))
+			return
+		}
+		c.metrics = m
+	}
+}
+
+type clientMetrics struct {
+	duration metric.Float64Histogram
+	size     metric.Int64Histogram
+	errors   metric.Int64Counter
+}
+
+func newClientMetrics(meter metric.Meter) (*clientMetrics, error) {
+	duration, err := meter.Float64Histogram(
+		"fmp.client.request.duration",
+		metric.WithDescription("Duration of FMP API calls, including retries."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	size, err := meter.Int64Histogram(
+		"fmp.client.response.size",
+		metric.WithDescription("Size of FMP API response bodies."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := meter.Int64Counter(
+		"fmp.client.errors",
+		metric.WithDescription("Number of failed FMP API calls by error category."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &clientMetrics{
+		duration: duration,
+		size:     size,
+		errors:   errs,
+	}, nil
+}
+
+// callObservation records the telemetry of a single call. It is a no-op unless the client is instrumented.
+type callObservation struct {
+	client   *Client
+	span     trace.Span
+	start    time.Time
+	attrs    []attribute.KeyValue
+	cacheHit bool
+}
+
+// observe starts recording a call and returns the context that carries its span.
+func (c *Client) observe(ctx context.Context, method, endpoint string) (context.Context, *callObservation) {
+	if c.tracer == nil && c.metrics == nil {
+		return ctx, nil
+	}
+	o := &callObservation{
+		client: c,
+		start:  time.Now(),
+		attrs:  []attribute.KeyValue{attrMethod.String(method), attrEndpoint.String(endpoint)},
+	}
+	if c.tracer != nil {
+		ctx, o.span = c.tracer.Start(ctx, method+" "+endpoint,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(o.attrs...),
+		)
+	}
+	return ctx, o
+}
+
+func (o *callObservation) markCacheHit() {
+	if o != nil {
+		o.cacheHit = true
+	}
+}
+
+// end records the outcome of the call.
+func (o *callObservation) end(ctx context.Context, res *resty.Response, err error) {
+	var size int64
+	if res != nil {
+		size = int64(len(res.Body()))
+	}
+	o.endWithSize(ctx, res, size, err)
+}
+
+// endWithSize records the outcome of the call with the size of its response body, e.g. the number of bytes read from
+// a streamed body.
+func (o *callObservation) endWithSize(ctx context.Context, res *resty.Response, size int64, err error) {
+	if o == nil {
+		return
+	}
+	attrs := o.attrs
+	if res != nil && res.RawResponse != nil {
+		attrs = append(attrs, attrStatusCode.Int(res.StatusCode()))
+	}
+	if o.span != nil {
+		o.span.SetAttributes(attrs...)
+		o.span.SetAttributes(attrCacheHit.Bool(o.cacheHit))
+		if err != nil {
+			o.span.RecordError(err)
+			o.span.SetStatus(codes.Error, err.Error())
+		}
+		o.span.End()
+	}
+	if m := o.client.metrics; m != nil {
+		m.duration.Record(ctx, time.Since(o.start).Seconds(), metric.WithAttributes(attrs...))
+		if res != nil {
+			m.size.Record(ctx, size, metric.WithAttributes(attrs...))
+		}
+		if err != nil {
+			m.errors.Add(ctx, 1, metric.WithAttributes(append(attrs, attrErrorCategory.String(errorCategory(err)))...))
+		}
+	}
+}
+
+// telemetryMiddleware records the number of retries on the span of the call.
+func telemetryMiddleware(next RoundTrip) RoundTrip {
+	return func(ctx context.Context, req *Request) (*resty.Response, error) {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.SetAttributes(attrRetryCount.Int(req.Attempt - 1))
+			if req.Attempt > 1 {
+				span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", req.Attempt)))
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// errorCategory returns a low-cardinality name of the error for use in metric attributes.
+func errorCategory(err error) string {
+	var responseError *fmperrors.ResponseError
+	switch {
+	case errors.As(err, &responseError) && responseError.Category != nil:
+		return responseError.Category.Error()
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	}
+	return "transport"
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestClient_CallTelemetry(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"Error Message": "Limit Reach . Please upgrade your plan"}`))
+	}))
+	defer srv.Close()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	policy := testRetryPolicy(nil)
+	policy.MaxRetries = 1
+	c := newTestClient(t, srv.URL,
+		WithRetryPolicy(policy),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+
+	_, err := c.Call(context.Background(), http.MethodGet, "/stable/quote", nil, nil)
+	require.Error(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	span := ended[0]
+	assert.Equal(t, "GET /stable/quote", span.Name())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Attributes(), attrEndpoint.String("/stable/quote"))
+	assert.Contains(t, span.Attributes(), attrStatusCode.Int(http.StatusTooManyRequests))
+	assert.Contains(t, span.Attributes(), attrRetryCount.Int(1))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	assert.Contains(t, metrics, "fmp.client.request.duration")
+	assert.Contains(t, metrics, "fmp.client.response.size")
+	require.Contains(t, metrics, "fmp.client.errors")
+	errs, ok := metrics["fmp.client.errors"].(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, errs.DataPoints, 1)
+	category, _ := errs.DataPoints[0].Attributes.Value(attrErrorCategory)
+	assert.Equal(t, attribute.StringValue("rate limited"), category)
+}
+
+func TestClient_OpenTelemetry(t *testing.T) {
+	const body = `[{"symbol":"AAPL"},{"symbol":"MSFT"}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	c := newTestClient(t, srv.URL,
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+
+	_, r, err := c.Open(context.Background(), http.MethodGet, "/stable/profile-bulk", nil)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Empty(t, spans.Ended(), "the call ends once the body is closed")
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close())
+	require.Len(t, spans.Ended(), 1)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	var size metricdata.Histogram[int64]
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == "fmp.client.response.size" {
+			size, _ = m.Data.(metricdata.Histogram[int64])
+		}
+	}
+	require.Len(t, size.DataPoints, 1)
+	assert.Equal(t, uint64(1), size.DataPoints[0].Count)
+	assert.Equal(t, int64(len(body)), size.DataPoints[0].Sum, "the size is the number of bytes read from the body")
+}
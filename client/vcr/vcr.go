@@ -0,0 +1,283 @@
+// Package vcr records responses of the FMP API into cassette files and replays them, so that code using the
+// REST client can be tested deterministically and without network access against real payloads.
+//
+// A Recorder is an http.RoundTripper and is plugged into the REST client with rest.WithTransport.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CassetteVersion is the version of the cassette format written by the recorder.
+// Cassettes of other versions are rejected and need to be recorded again.
+const CassetteVersion = 1
+
+// scrubbedParams are the query params that are never written to a cassette.
+var scrubbedParams = []string{"apikey"}
+
+// RecordedHeaders are the only response headers written to a cassette, the ones the client reads. The others, e.g.
+// Set-Cookie or the request IDs of the CDN, may identify the account and are dropped.
+var RecordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// ErrInteractionNotFound is returned in replay mode for requests that are not in the cassette.
+var ErrInteractionNotFound = errors.New("interaction not found in cassette")
+
+// Mode defines whether the recorder sends requests to the API or replays them from the cassette.
+type Mode string
+
+const (
+	// ModeRecord sends every request to the API and records it. Existing interactions are discarded.
+	ModeRecord Mode = "record"
+
+	// ModeReplay replays requests from the cassette and fails the ones that are not in it.
+	ModeReplay Mode = "replay"
+
+	// ModeReplayOrRecord replays requests from the cassette and records the ones that are not in it.
+	ModeReplayOrRecord Mode = "replay-or-record"
+)
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// Request is a recorded request. The URL does not contain the API key.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Recorder records and replays interactions with the API.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+
+	lock     sync.Mutex
+	cassette *Cassette
+	replayed map[*Interaction]bool
+	modified bool
+}
+
+type Option func(r *Recorder)
+
+// WithTransport sets the transport used to send requests to the API. It defaults to http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// New returns a recorder of the cassette at path. The cassette is loaded unless the mode is ModeRecord.
+// In ModeReplay the cassette must exist.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		cassette:  &Cassette{Version: CassetteVersion},
+		replayed:  make(map[*Interaction]bool),
+	}
+	for _, o := range opts {
+		o(r)
+	}
+
+	switch mode {
+	case ModeRecord:
+		return r, nil
+	case ModeReplay, ModeReplayOrRecord:
+	default:
+		return nil, fmt.Errorf("unknown mode: %q", mode)
+	}
+
+	cassette, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) && mode == ModeReplayOrRecord {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.cassette = cassette
+	return r, nil
+}
+
+// Load reads the cassette at path.
+func Load(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(b, &cassette); err != nil {
+		return nil, fmt.Errorf("unmarshaling cassette: %w", err)
+	}
+	if cassette.Version != CassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d, expected %d: record the cassette again", cassette.Version, CassetteVersion)
+	}
+	return &cassette, nil
+}
+
+// Mode returns the mode of the recorder.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// RoundTrip replays the response of the request from the cassette or sends the request and records the response,
+// depending on the mode of the recorder.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode != ModeRecord {
+		if interaction, ok := r.find(req); ok {
+			return interaction.Response.toHTTP(req), nil
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, scrubURL(req.URL))
+		}
+	}
+
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Headers:    recordedHeaders(res.Header),
+			Body:       string(body),
+		},
+		RecordedAt: time.Now().UTC(),
+	})
+	r.modified = true
+	return res, nil
+}
+
+// Save writes the cassette to its file if interactions were recorded.
+func (r *Recorder) Save() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.modified {
+		return nil
+	}
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil { //nolint:mnd // standard directory permissions
+		return fmt.Errorf("creating cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(b, '\n'), 0o600); err != nil { //nolint:mnd // standard file permissions
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	r.modified = false
+	return nil
+}
+
+// find returns the interaction recorded for the request. Identical requests are replayed in the order they were
+// recorded, and the last one is replayed again once all of them have been used.
+func (r *Recorder) find(req *http.Request) (*Interaction, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := matchKey(req.Method, req.URL)
+	var last *Interaction
+	for _, interaction := range r.cassette.Interactions {
+		u, err := url.Parse(interaction.Request.URL)
+		if err != nil || matchKey(interaction.Request.Method, u) != key {
+			continue
+		}
+		if !r.replayed[interaction] {
+			r.replayed[interaction] = true
+			return interaction, true
+		}
+		last = interaction
+	}
+	return last, last != nil
+}
+
+func (res Response) toHTTP(req *http.Request) *http.Response {
+	header := res.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+		StatusCode:    res.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(res.Body)),
+		ContentLength: int64(len(res.Body)),
+		Request:       req,
+	}
+}
+
+// matchKey identifies a request regardless of the host it was sent to and of the order of its query params.
+func matchKey(method string, u *url.URL) string {
+	query := u.Query()
+	for _, param := range scrubbedParams {
+		query.Del(param)
+	}
+	return method + " " + u.Path + "?" + query.Encode()
+}
+
+// recordedHeaders returns the headers of the response that are written to a cassette.
+func recordedHeaders(header http.Header) http.Header {
+	recorded := http.Header{}
+	for _, key := range RecordedHeaders {
+		if values := header.Values(key); len(values) > 0 {
+			recorded[http.CanonicalHeaderKey(key)] = slices.Clone(values)
+		}
+	}
+	return recorded
+}
+
+// scrubURL returns the URL without the params that must not be recorded.
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	for _, param := range scrubbedParams {
+		query.Del(param)
+	}
+	scrubbed.RawQuery = query.Encode()
+	scrubbed.User = nil
+	return scrubbed.String()
+}
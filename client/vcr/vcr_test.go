@@ -0,0 +1,131 @@
+package vcr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCountingServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Cf-Ray", "8f1b2c3d4e5f6a7b-AMS")
+		_, _ = io.WriteString(w, `[{"symbol":"`+r.URL.Query().Get("symbol")+`"}]`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func send(t *testing.T, rt http.RoundTripper, rawURL string) (string, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil) //nolint:noctx // test request
+	require.NoError(t, err)
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(b), nil
+}
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	server, hits := newCountingServer(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "quote.json")
+
+	recorder, err := New(path, ModeRecord)
+	require.NoError(t, err)
+	body, err := send(t, recorder, server.URL+"/stable/quote?symbol=AAPL&apikey=secret")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"symbol":"AAPL"}]`, body)
+	require.NoError(t, recorder.Save())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "secret", "the API key must be scrubbed")
+	cassette, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, CassetteVersion, cassette.Version)
+	require.Len(t, cassette.Interactions, 1)
+	assert.Equal(t, server.URL+"/stable/quote?symbol=AAPL", cassette.Interactions[0].Request.URL)
+	assert.Equal(t, http.Header{"Content-Type": {"application/json"}}, cassette.Interactions[0].Response.Headers, "only the allowed headers must be recorded")
+
+	// Replays do not depend on the host nor on the API key.
+	replayer, err := New(path, ModeReplay)
+	require.NoError(t, err)
+	body, err = send(t, replayer, "https://financialmodelingprep.com/stable/quote?apikey=other&symbol=AAPL")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"symbol":"AAPL"}]`, body)
+	assert.Equal(t, int32(1), hits.Load())
+
+	_, err = send(t, replayer, "https://financialmodelingprep.com/stable/quote?symbol=MSFT&apikey=other")
+	require.ErrorIs(t, err, ErrInteractionNotFound)
+	assert.NotContains(t, err.Error(), "other")
+}
+
+func TestRecorder_ReplayOrRecord(t *testing.T) {
+	server, hits := newCountingServer(t)
+	path := filepath.Join(t.TempDir(), "quote.json")
+
+	recorder, err := New(path, ModeReplayOrRecord)
+	require.NoError(t, err)
+	for _, symbol := range []string{"AAPL", "AAPL", "MSFT"} {
+		body, err := send(t, recorder, server.URL+"/stable/quote?symbol="+symbol)
+		require.NoError(t, err)
+		assert.Contains(t, body, symbol)
+	}
+	assert.Equal(t, int32(2), hits.Load(), "repeated requests must be replayed")
+	require.NoError(t, recorder.Save())
+
+	recorder, err = New(path, ModeReplayOrRecord)
+	require.NoError(t, err)
+	for _, symbol := range []string{"AAPL", "MSFT", "NVDA"} {
+		body, err := send(t, recorder, server.URL+"/stable/quote?symbol="+symbol)
+		require.NoError(t, err)
+		assert.Contains(t, body, symbol)
+	}
+	assert.Equal(t, int32(3), hits.Load(), "only the missing request must be recorded")
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	outdated := filepath.Join(dir, "outdated.json")
+	require.NoError(t, os.WriteFile(outdated, []byte(`{"version":0,"interactions":[]}`), 0o600))
+
+	tests := []struct {
+		name    string
+		path    string
+		mode    Mode
+		wantErr string
+	}{
+		{name: "success:record missing cassette", path: filepath.Join(dir, "missing.json"), mode: ModeRecord},
+		{name: "success:replay-or-record missing cassette", path: filepath.Join(dir, "missing.json"), mode: ModeReplayOrRecord},
+		{name: "error:replay missing cassette", path: filepath.Join(dir, "missing.json"), mode: ModeReplay, wantErr: "reading cassette"},
+		{name: "error:outdated cassette", path: outdated, mode: ModeReplay, wantErr: "unsupported cassette version"},
+		{name: "error:unknown mode", path: outdated, mode: Mode("rewind"), wantErr: "unknown mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.path, tt.mode)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.True(t, strings.Contains(err.Error(), tt.wantErr), err.Error())
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
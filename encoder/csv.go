@@ -0,0 +1,306 @@
+package encoder
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// csvFieldsCache caches the decodable fields of struct types.
+var csvFieldsCache sync.Map // map[reflect.Type]*csvFields
+
+// csvField is a struct field a CSV column can be decoded into.
+type csvField struct {
+	name   string
+	index  []int
+	decode func(v reflect.Value, s string) error
+}
+
+type csvFields struct {
+	list   []*csvField
+	byName map[string]*csvField
+}
+
+// RowError is the error of a record that could not be decoded. The decoder goes on with the next record.
+type RowError struct {
+	// Row is the number of the record, starting at 1 for the first record after the header.
+	Row int
+
+	// Line is the line of the record in the file, starting at 1 for the header.
+	Line int
+
+	// Record is the raw record. It is empty if the record could not be read as CSV.
+	Record []string
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d (line %d): %v", e.Row, e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// CSVDecoder decodes the records of a CSV file into structs of type T.
+//
+// The columns of the header are mapped to the struct fields once per file, by the name of their json tag or of the
+// field like encoding/json does. Fields implementing encoding.TextUnmarshaler decode the text of their column,
+// so that types such as types.EmptyOr and types.Bool behave as if the record were a JSON object of strings.
+// Columns without a matching field are ignored.
+type CSVDecoder[T any] struct {
+	reader  *csv.Reader
+	mapping *CSVMapping[T]
+	row     int
+}
+
+// NewCSVDecoder reads the header of the CSV file and returns a decoder of its records.
+func NewCSVDecoder[T any](r io.Reader) (*CSVDecoder[T], error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	// Records with a wrong number of fields are reported by the mapping as a RowError.
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	mapping, err := NewCSVMapping[T](slices.Clone(header))
+	if err != nil {
+		return nil, err
+	}
+	return &CSVDecoder[T]{reader: reader, mapping: mapping}, nil
+}
+
+// Header returns the header of the CSV file.
+func (d *CSVDecoder[T]) Header() []string {
+	return d.mapping.header
+}
+
+// Decode decodes the next record into v. It returns io.EOF once all records have been decoded.
+//
+// Records that cannot be read or decoded fail with a *RowError, after which Decode can be called again to go on
+// with the next record. Other errors, such as the ones of the underlying reader, are final.
+func (d *CSVDecoder[T]) Decode(v *T) error {
+	record, err := d.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	d.row++
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &RowError{Row: d.row, Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return fmt.Errorf("reading record: %w", err)
+	}
+	if err := d.mapping.Decode(record, v); err != nil {
+		line, _ := d.reader.FieldPos(0)
+		return &RowError{Row: d.row, Line: line, Record: slices.Clone(record), Err: err}
+	}
+	return nil
+}
+
+// CSVMapping maps the columns of a CSV header to the fields of structs of type T.
+type CSVMapping[T any] struct {
+	header []string
+	fields []*csvField
+}
+
+// NewCSVMapping returns the mapping of the header columns to the fields of T, which must be a struct.
+func NewCSVMapping[T any](header []string) (*CSVMapping[T], error) {
+	fields, err := cachedCSVFields(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	m := &CSVMapping[T]{header: header, fields: make([]*csvField, len(header))}
+	for i, column := range header {
+		m.fields[i] = fields.lookup(column)
+	}
+	return m, nil
+}
+
+// Decode decodes the record into v. The record must have as many fields as the header.
+func (m *CSVMapping[T]) Decode(record []string, v *T) error {
+	if len(record) != len(m.fields) {
+		return fmt.Errorf("invalid record length: expected %d, got %d", len(m.fields), len(record))
+	}
+	rv := reflect.ValueOf(v).Elem()
+	for i, field := range m.fields {
+		if field == nil {
+			continue
+		}
+		if err := field.decode(rv.FieldByIndex(field.index), record[i]); err != nil {
+			return fmt.Errorf("decoding column %q: %w", m.header[i], err)
+		}
+	}
+	return nil
+}
+
+// lookup returns the field of the column. Like encoding/json, an exact match is preferred over a case-insensitive one.
+func (f *csvFields) lookup(column string) *csvField {
+	if field, ok := f.byName[column]; ok {
+		return field
+	}
+	for _, field := range f.list {
+		if strings.EqualFold(field.name, column) {
+			return field
+		}
+	}
+	return nil
+}
+
+func cachedCSVFields(t reflect.Type) (*csvFields, error) {
+	if fields, ok := csvFieldsCache.Load(t); ok {
+		return fields.(*csvFields), nil //nolint:forcetypeassert // the cache only holds *csvFields
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot decode CSV records into %s: not a struct", t)
+	}
+	fields := &csvFields{byName: make(map[string]*csvField)}
+	if err := collectCSVFields(t, nil, fields); err != nil {
+		return nil, err
+	}
+	actual, _ := csvFieldsCache.LoadOrStore(t, fields)
+	return actual.(*csvFields), nil //nolint:forcetypeassert // the cache only holds *csvFields
+}
+
+// collectCSVFields collects the exported fields of the struct type, including the ones of embedded structs.
+func collectCSVFields(t reflect.Type, index []int, fields *csvFields) error {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			if err := collectCSVFields(sf.Type, fieldIndex, fields); err != nil {
+				return err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		decode, err := newCSVFieldDecoder(sf.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		if _, ok := fields.byName[name]; ok {
+			continue
+		}
+		field := &csvField{name: name, index: fieldIndex, decode: decode}
+		fields.list = append(fields.list, field)
+		fields.byName[name] = field
+	}
+	return nil
+}
+
+// newCSVFieldDecoder returns the function that decodes the text of a column into a value of type t.
+func newCSVFieldDecoder(t reflect.Type) (func(v reflect.Value, s string) error, error) {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return func(v reflect.Value, s string) error {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)) //nolint:forcetypeassert // checked above
+		}, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		decodeElem, err := newCSVFieldDecoder(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value, s string) error {
+			if s == "" {
+				v.SetZero()
+				return nil
+			}
+			p := reflect.New(t.Elem())
+			if err := decodeElem(p.Elem(), s); err != nil {
+				return err
+			}
+			v.Set(p)
+			return nil
+		}, nil
+	case reflect.String:
+		return func(v reflect.Value, s string) error {
+			v.SetString(s)
+			return nil
+		}, nil
+	case reflect.Bool:
+		return func(v reflect.Value, s string) error {
+			if s == "" {
+				v.SetBool(false)
+				return nil
+			}
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+			return nil
+		}, nil
+	}
+	return newCSVNumberDecoder(t)
+}
+
+// newCSVNumberDecoder returns the function that decodes the text of a column into a number of type t.
+// Empty columns decode into zero.
+func newCSVNumberDecoder(t reflect.Type) (func(v reflect.Value, s string) error, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value, s string) error {
+			if s == "" {
+				v.SetInt(0)
+				return nil
+			}
+			n, err := strconv.ParseInt(s, 10, t.Bits())
+			if err != nil {
+				return err
+			}
+			v.SetInt(n)
+			return nil
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(v reflect.Value, s string) error {
+			if s == "" {
+				v.SetUint(0)
+				return nil
+			}
+			n, err := strconv.ParseUint(s, 10, t.Bits())
+			if err != nil {
+				return err
+			}
+			v.SetUint(n)
+			return nil
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		return func(v reflect.Value, s string) error {
+			if s == "" {
+				v.SetFloat(0)
+				return nil
+			}
+			f, err := strconv.ParseFloat(s, t.Bits())
+			if err != nil {
+				return err
+			}
+			v.SetFloat(f)
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
@@ -0,0 +1,246 @@
+package encoder_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/encoder"
+	"go.tradeforge.dev/fmp/model"
+	"go.tradeforge.dev/fmp/pkg/types"
+)
+
+var bulkProfilesFixture = filepath.Join("..", "fmptest", "fixtures", "stable", "profile-bulk", "0.csv")
+
+func decodeProfile(t *testing.T, header, record []string) (*model.BulkCompanyProfileResponse, error) {
+	t.Helper()
+	mapping, err := encoder.NewCSVMapping[model.BulkCompanyProfileResponse](header)
+	require.NoError(t, err)
+	var profile model.BulkCompanyProfileResponse
+	return &profile, mapping.Decode(record, &profile)
+}
+
+func TestCSVMapping_Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		record  []string
+		testFn  func(profile *model.BulkCompanyProfileResponse)
+		wantErr string
+	}{
+		{
+			name:   "success:empty-employee-count",
+			header: []string{"Symbol", "FullTimeEmployees"},
+			record: []string{"AAPL", ""},
+			testFn: func(profile *model.BulkCompanyProfileResponse) {
+				assert.Equal(t, "AAPL", profile.Symbol)
+				assert.True(t, profile.FullTimeEmployees.IsEmpty())
+			},
+		},
+		{
+			name:   "success:thousand-separated-employee-count",
+			header: []string{"symbol", "fullTimeEmployees"},
+			record: []string{"AAPL", "1,234"},
+			testFn: func(profile *model.BulkCompanyProfileResponse) {
+				assert.Equal(t, decimal.NewFromInt(1234), profile.FullTimeEmployees.Value().Value().Value())
+			},
+		},
+		{
+			name:   "success:ignore-failure-employee-count",
+			header: []string{"symbol", "fullTimeEmployees"},
+			record: []string{"AAPL", "{invalid-value}"},
+			testFn: func(profile *model.BulkCompanyProfileResponse) {
+				assert.False(t, profile.FullTimeEmployees.IsEmpty())
+				assert.Nil(t, profile.FullTimeEmployees.Value().Value())
+			},
+		},
+		{
+			name:   "success:exponentials-52w-range",
+			header: []string{"symbol", "range"},
+			record: []string{"AAPL", "1.23e-4-5.67e+8"},
+			testFn: func(profile *model.BulkCompanyProfileResponse) {
+				require.NotNil(t, profile.Range.Value())
+				assert.Equal(t, types.Range52w{Min: decimal.RequireFromString("1.23e-4"), Max: decimal.RequireFromString("5.67e+8"), Sep: "-"}, *profile.Range.Value())
+			},
+		},
+		{
+			name:   "success:empty-or-and-bool",
+			header: []string{"symbol", "price", "ceo", "ipoDate", "isEtf", "unknown"},
+			record: []string{"AAPL", "243.85", "", "1980-12-12", "true", "ignored"},
+			testFn: func(profile *model.BulkCompanyProfileResponse) {
+				assert.True(t, decimal.RequireFromString("243.85").Equal(*profile.Price.Value()))
+				assert.True(t, profile.Ceo.IsEmpty())
+				assert.Equal(t, types.Date("1980-12-12"), *profile.IpoDate.Value())
+				assert.True(t, profile.IsEtf.BoolValue())
+			},
+		},
+		{
+			name:    "error:invalid-bool",
+			header:  []string{"symbol", "isEtf"},
+			record:  []string{"AAPL", "maybe"},
+			wantErr: `decoding column "isEtf"`,
+		},
+		{
+			name:    "error:record-length",
+			header:  []string{"symbol", "isEtf"},
+			record:  []string{"AAPL"},
+			wantErr: "invalid record length",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeProfile(t, tt.header, tt.record)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.testFn(got)
+		})
+	}
+}
+
+func TestCSVDecoder_MatchesJSONDecoding(t *testing.T) {
+	b, err := os.ReadFile(bulkProfilesFixture)
+	require.NoError(t, err)
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	require.NoError(t, err)
+
+	decoder, err := encoder.NewCSVDecoder[model.BulkCompanyProfileResponse](bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, records[0], decoder.Header())
+	for _, record := range records[1:] {
+		want, err := model.ParseCompanyProfileCSVRecord(records[0], record)
+		require.NoError(t, err)
+
+		var got model.BulkCompanyProfileResponse
+		require.NoError(t, decoder.Decode(&got))
+		assert.Equal(t, *want, got)
+	}
+	assert.ErrorIs(t, decoder.Decode(new(model.BulkCompanyProfileResponse)), io.EOF)
+}
+
+func TestNewCSVMapping(t *testing.T) {
+	type embedded struct {
+		Name string `json:"name"`
+	}
+	type row struct {
+		embedded
+		Count   int      `json:"count"`
+		Ratio   *float64 `json:"ratio"`
+		Skipped string   `json:"-"`
+	}
+	mapping, err := encoder.NewCSVMapping[row]([]string{"name", "count", "ratio", "Skipped"})
+	require.NoError(t, err)
+	var got row
+	require.NoError(t, mapping.Decode([]string{"AAPL", "3", "", "value"}, &got))
+	assert.Equal(t, row{embedded: embedded{Name: "AAPL"}, Count: 3}, got)
+
+	_, err = encoder.NewCSVMapping[struct{ C chan int }]([]string{"c"})
+	assert.ErrorContains(t, err, "unsupported type")
+	_, err = encoder.NewCSVMapping[string]([]string{"c"})
+	assert.ErrorContains(t, err, "not a struct")
+}
+
+func TestCSVDecoder_RowErrors(t *testing.T) {
+	type row struct {
+		Symbol string `json:"symbol"`
+		Count  int    `json:"count"`
+	}
+	decoder, err := encoder.NewCSVDecoder[row](strings.NewReader("symbol,count\nAAPL,1\nMSFT,two\nNV\"DA,3\nAMZN\nTSLA,5\n"))
+	require.NoError(t, err)
+
+	var symbols []string
+	var rowErrs []*encoder.RowError
+	for {
+		var r row
+		err := decoder.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var rowErr *encoder.RowError
+			require.ErrorAs(t, err, &rowErr, "only row errors are expected")
+			rowErrs = append(rowErrs, rowErr)
+			continue
+		}
+		symbols = append(symbols, r.Symbol)
+	}
+	assert.Equal(t, []string{"AAPL", "TSLA"}, symbols)
+	require.Len(t, rowErrs, 3)
+	assert.Equal(t, []int{2, 3, 4}, []int{rowErrs[0].Row, rowErrs[1].Row, rowErrs[2].Row})
+	assert.Equal(t, []int{3, 4, 5}, []int{rowErrs[0].Line, rowErrs[1].Line, rowErrs[2].Line})
+	assert.Equal(t, []string{"MSFT", "two"}, rowErrs[0].Record)
+	assert.ErrorIs(t, rowErrs[1], csv.ErrBareQuote)
+	assert.Empty(t, rowErrs[1].Record)
+	assert.ErrorContains(t, rowErrs[2], "invalid record length")
+}
+
+func loadBulkProfiles(b *testing.B) ([]byte, []string, [][]string) {
+	b.Helper()
+	raw, err := os.ReadFile(bulkProfilesFixture)
+	require.NoError(b, err)
+	records, err := csv.NewReader(bytes.NewReader(raw)).ReadAll()
+	require.NoError(b, err)
+	return raw, records[0], records[1:]
+}
+
+func BenchmarkParseCompanyProfileCSVRecord(b *testing.B) {
+	_, header, records := loadBulkProfiles(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		for _, record := range records {
+			if _, err := model.ParseCompanyProfileCSVRecord(header, record); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCSVMapping_Decode(b *testing.B) {
+	_, header, records := loadBulkProfiles(b)
+	mapping, err := encoder.NewCSVMapping[model.BulkCompanyProfileResponse](header)
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		for _, record := range records {
+			var profile model.BulkCompanyProfileResponse
+			if err := mapping.Decode(record, &profile); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCSVDecoder(b *testing.B) {
+	raw, _, _ := loadBulkProfiles(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		decoder, err := encoder.NewCSVDecoder[model.BulkCompanyProfileResponse](strings.NewReader(string(raw)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for {
+			var profile model.BulkCompanyProfileResponse
+			err := decoder.Decode(&profile)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
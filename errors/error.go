@@ -3,39 +3,8 @@ package errors
 import (
 	"errors"
 	"fmt"
-
-	"go.tradeforge.dev/fmp/model"
 )
 
-// ResponseError represents an API response with an error status code.
-type ResponseError struct {
-	model.BaseResponse
-
-	// An HTTP status code for unsuccessful requests.
-	StatusCode int
-}
-
-// Error returns the details of an error response.
-func (e *ResponseError) Error() string {
-	if e.Message != "" {
-		return fmt.Sprintf("request %s failed with code %d: %s", e.RequestID, e.StatusCode, e.Message)
-	}
-	return fmt.Sprintf("request %s failed with code %d", e.RequestID, e.StatusCode)
-}
-
-func AsResponseError(obj any) (*ResponseError, bool) {
-	err, ok := obj.(error)
-	if !ok {
-		return nil, false
-	}
-
-	responseError := &ResponseError{}
-	if ok := errors.As(err, &responseError); !ok {
-		return nil, false
-	}
-	return responseError, true
-}
-
 // Code is useful for converting to HTTP status code.
 // In general it's a value which is machine readable.
 type Code string
@@ -0,0 +1,179 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error categories of FMP responses. Use errors.Is to match a ResponseError against them.
+var (
+	ErrRateLimited     = errors.New("rate limited")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrPremiumEndpoint = errors.New("premium endpoint")
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidParams   = errors.New("invalid params")
+	ErrUpstream        = errors.New("upstream error")
+)
+
+// maxErrorMessageLength caps the length of non-JSON error bodies kept in the error message.
+const maxErrorMessageLength = 512
+
+// ResponseError represents an API response with an error status code.
+type ResponseError struct {
+	// Category is one of the Err* sentinel errors.
+	Category error
+
+	// An HTTP status code for unsuccessful requests.
+	StatusCode int
+
+	// The error message sent by FMP.
+	ErrorMessage string
+
+	// The endpoint path of the request, e.g. /stable/quote.
+	Path string
+
+	// The request URL with the API key redacted.
+	URL string
+}
+
+// NewResponseError builds a response error from the status code and body of an FMP response.
+func NewResponseError(statusCode int, body []byte, path, rawURL string) *ResponseError {
+	msg := parseErrorMessage(body)
+	return &ResponseError{
+		Category:     categorize(statusCode, msg),
+		StatusCode:   statusCode,
+		ErrorMessage: msg,
+		Path:         path,
+		URL:          RedactURL(rawURL),
+	}
+}
+
+// Error returns the details of an error response.
+func (e *ResponseError) Error() string {
+	if e.ErrorMessage != "" {
+		return fmt.Sprintf("request to %s failed with code %d: %v: %s", e.Path, e.StatusCode, e.Category, e.ErrorMessage)
+	}
+	return fmt.Sprintf("request to %s failed with code %d: %v", e.Path, e.StatusCode, e.Category)
+}
+
+// Unwrap returns the category of the error.
+func (e *ResponseError) Unwrap() error {
+	return e.Category
+}
+
+func AsResponseError(obj any) (*ResponseError, bool) {
+	err, ok := obj.(error)
+	if !ok {
+		return nil, false
+	}
+
+	responseError := &ResponseError{}
+	if ok := errors.As(err, &responseError); !ok {
+		return nil, false
+	}
+	return responseError, true
+}
+
+// HasErrorMessage reports whether the body is an FMP error object: one with an "Error Message", or one whose only key
+// is "message" or "error". FMP sometimes sends those with a successful status code. Other objects with such a key
+// are payloads rather than errors.
+func HasErrorMessage(body []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false
+	}
+	if raw, ok := fields["Error Message"]; ok {
+		return isNonEmptyString(raw)
+	}
+	if len(fields) != 1 {
+		return false
+	}
+	for _, key := range []string{"message", "error"} {
+		if raw, ok := fields[key]; ok {
+			return isNonEmptyString(raw)
+		}
+	}
+	return false
+}
+
+func isNonEmptyString(raw json.RawMessage) bool {
+	var s string
+	return json.Unmarshal(raw, &s) == nil && s != ""
+}
+
+// RedactURL replaces the API key in the URL query.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	if q.Has("apikey") {
+		q.Set("apikey", "REDACTED")
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+type errorBody struct {
+	ErrorMessage string `json:"Error Message"`
+	Error        string `json:"error"`
+	Message      string `json:"message"`
+}
+
+func (b errorBody) message() string {
+	switch {
+	case b.ErrorMessage != "":
+		return b.ErrorMessage
+	case b.Error != "":
+		return b.Error
+	}
+	return b.Message
+}
+
+func parseErrorMessage(body []byte) string {
+	var v errorBody
+	if err := json.Unmarshal(body, &v); err == nil {
+		return v.message()
+	}
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > maxErrorMessageLength {
+		msg = msg[:maxErrorMessageLength]
+	}
+	return msg
+}
+
+// categorize maps a status code and an FMP error message to an error category. The message takes precedence because
+// FMP does not use status codes consistently across its API versions.
+func categorize(statusCode int, msg string) error {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "limit reach"):
+		return ErrRateLimited
+	case strings.Contains(lower, "invalid api key"):
+		return ErrUnauthorized
+	case strings.Contains(lower, "exclusive endpoint"),
+		strings.Contains(lower, "restricted endpoint"),
+		strings.Contains(lower, "premium"),
+		strings.Contains(lower, "subscription"):
+		return ErrPremiumEndpoint
+	}
+
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case statusCode == http.StatusPaymentRequired:
+		return ErrPremiumEndpoint
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError:
+		return ErrInvalidParams
+	}
+	return ErrUpstream
+}
@@ -0,0 +1,101 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResponseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       error
+		wantMsg    string
+	}{
+		{
+			name:       "rate-limited:status",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"Error Message": "Limit Reach . Please upgrade your plan or visit our documentation for more details at https://site.financialmodelingprep.com/"}`,
+			want:       ErrRateLimited,
+			wantMsg:    "Limit Reach . Please upgrade your plan or visit our documentation for more details at https://site.financialmodelingprep.com/",
+		},
+		{
+			name:       "rate-limited:message",
+			statusCode: http.StatusOK,
+			body:       `{"Error Message": "Limit Reach . Please upgrade your plan"}`,
+			want:       ErrRateLimited,
+			wantMsg:    "Limit Reach . Please upgrade your plan",
+		},
+		{
+			name:       "unauthorized",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"Error Message": "Invalid API KEY. Feel free to create a Free API Key."}`,
+			want:       ErrUnauthorized,
+			wantMsg:    "Invalid API KEY. Feel free to create a Free API Key.",
+		},
+		{
+			name:       "premium-endpoint:status",
+			statusCode: http.StatusPaymentRequired,
+			body:       `Restricted Endpoint: This endpoint is not available under your current subscription`,
+			want:       ErrPremiumEndpoint,
+			wantMsg:    "Restricted Endpoint: This endpoint is not available under your current subscription",
+		},
+		{
+			name:       "premium-endpoint:forbidden",
+			statusCode: http.StatusForbidden,
+			body:       `{"Error Message": "Exclusive Endpoint : This endpoint is not available under your current subscription agreement"}`,
+			want:       ErrPremiumEndpoint,
+			wantMsg:    "Exclusive Endpoint : This endpoint is not available under your current subscription agreement",
+		},
+		{
+			name:       "not-found",
+			statusCode: http.StatusNotFound,
+			want:       ErrNotFound,
+		},
+		{
+			name:       "invalid-params",
+			statusCode: http.StatusBadRequest,
+			body:       `{"message": "Invalid parameters"}`,
+			want:       ErrInvalidParams,
+			wantMsg:    "Invalid parameters",
+		},
+		{
+			name:       "upstream",
+			statusCode: http.StatusBadGateway,
+			body:       `<html>Bad Gateway</html>`,
+			want:       ErrUpstream,
+			wantMsg:    "<html>Bad Gateway</html>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewResponseError(tt.statusCode, []byte(tt.body), "/stable/quote", "https://financialmodelingprep.com/stable/quote?apikey=secret&symbol=AAPL")
+			wrapped := fmt.Errorf("wrapped: %w", err)
+
+			assert.ErrorIs(t, wrapped, tt.want)
+			assert.Equal(t, tt.wantMsg, err.ErrorMessage)
+			assert.Equal(t, "/stable/quote", err.Path)
+			assert.Equal(t, "https://financialmodelingprep.com/stable/quote?apikey=REDACTED&symbol=AAPL", err.URL)
+
+			responseError, ok := AsResponseError(wrapped)
+			assert.True(t, ok)
+			assert.Equal(t, tt.statusCode, responseError.StatusCode)
+		})
+	}
+}
+
+func TestHasErrorMessage(t *testing.T) {
+	assert.True(t, HasErrorMessage([]byte(`{"Error Message": "Limit Reach"}`)))
+	assert.False(t, HasErrorMessage([]byte(`[{"symbol": "AAPL"}]`)))
+	assert.False(t, HasErrorMessage([]byte(`{"symbol": "AAPL"}`)))
+	assert.True(t, HasErrorMessage([]byte(`{"message": "Invalid API KEY"}`)))
+	assert.True(t, HasErrorMessage([]byte(`{"error": "Limit Reach"}`)))
+	assert.False(t, HasErrorMessage([]byte(`{"symbol": "AAPL", "message": "Apple reports earnings"}`)), "payloads with a message are not errors")
+	assert.False(t, HasErrorMessage([]byte(`{"error": ""}`)))
+	assert.False(t, errors.Is(NewResponseError(http.StatusNotFound, nil, "", ""), ErrUpstream))
+}
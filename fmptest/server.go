@@ -0,0 +1,396 @@
+// Package fmptest provides a stand-in for the FMP API that serves canned responses, so that code using the
+// market clients can be tested without network access or an API key.
+package fmptest
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAPIKey is the API key the server accepts unless configured otherwise.
+	DefaultAPIKey = "test-api-key" //nolint:gosec // not a real credential
+
+	// WebsocketPath is the path of the websocket endpoint.
+	WebsocketPath = "/websockets"
+
+	// DefaultPageSize is the page size of paginated endpoints that are called with a page but no limit.
+	DefaultPageSize = 100
+)
+
+// Error messages returned by FMP.
+const (
+	MessageLimitReached       = "Limit Reach . Please upgrade your plan or visit our documentation for more details at https://site.financialmodelingprep.com/"
+	MessageInvalidAPIKey      = "Invalid API KEY. Feel free to create a Free API Key or visit https://site.financialmodelingprep.com/faqs?search=why-is-my-api-key-invalid for more information."
+	MessageRestrictedEndpoint = "Restricted Endpoint: This endpoint is not available under your current subscription please visit our subscription page to upgrade your plan at https://site.financialmodelingprep.com/developer/docs/pricing"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+// Fault is a simulated failure of an endpoint.
+type Fault struct {
+	// StatusCode is the status code of the response. Zero means the request succeeds, e.g. to only add latency.
+	StatusCode int
+
+	// Message is the error message of the response body. Defaults to the status text.
+	Message string
+
+	// RetryAfter sets the Retry-After header of the response.
+	RetryAfter time.Duration
+
+	// Latency delays the response.
+	Latency time.Duration
+
+	// Times is the number of requests the fault applies to. Zero means all requests.
+	Times int
+}
+
+// Server is an httptest.Server that serves the FMP API from fixture files.
+//
+// Fixtures are looked up by request path, e.g. /stable/quote is served from fixtures/stable/quote.json.
+// Array responses are filtered by the symbol, symbols, exchange, from and to query params and paginated by
+// the page and limit query params, like the API does.
+type Server struct {
+	*httptest.Server
+
+	apiKey            string
+	latency           time.Duration
+	heartbeatInterval time.Duration
+	maxSymbols        int
+	maxSubscriptions  int
+	fixtures          fs.FS
+
+	lock   sync.Mutex
+	faults map[string][]*Fault
+	hits   map[string]int
+
+	websockets *websocketHub
+}
+
+type Option func(s *Server)
+
+// WithAPIKey sets the API key the server accepts.
+func WithAPIKey(apiKey string) Option {
+	return func(s *Server) {
+		s.apiKey = apiKey
+	}
+}
+
+// WithLatency delays every response.
+func WithLatency(latency time.Duration) Option {
+	return func(s *Server) {
+		s.latency = latency
+	}
+}
+
+// WithFixtures replaces the built-in fixtures. Files are looked up by request path without the leading slash.
+func WithFixtures(fsys fs.FS) Option {
+	return func(s *Server) {
+		s.fixtures = fsys
+	}
+}
+
+// WithHeartbeatInterval makes the websocket endpoint send heartbeats at the given interval.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.heartbeatInterval = interval
+	}
+}
+
+// WithMaxSubscriptionSymbols makes the websocket endpoint reject subscribe and unsubscribe messages with more than
+// the given number of symbols.
+func WithMaxSubscriptionSymbols(n int) Option {
+	return func(s *Server) {
+		s.maxSymbols = n
+	}
+}
+
+// WithMaxSubscriptions makes the websocket endpoint reject subscribe messages that would subscribe a connection to
+// more than the given number of symbols, like the plans of FMP do.
+func WithMaxSubscriptions(n int) Option {
+	return func(s *Server) {
+		s.maxSubscriptions = n
+	}
+}
+
+// NewServer starts and returns a new server. The caller should call Close when finished, to shut it down.
+func NewServer(opts ...Option) *Server {
+	builtin, err := fs.Sub(fixtures, "fixtures")
+	if err != nil {
+		panic(fmt.Errorf("loading fixtures: %w", err))
+	}
+	s := &Server{
+		apiKey:   DefaultAPIKey,
+		fixtures: builtin,
+		faults:   make(map[string][]*Fault),
+		hits:     make(map[string]int),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.websockets = newWebsocketHub(s.apiKey, s.heartbeatInterval, s.maxSymbols, s.maxSubscriptions)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close closes all websocket connections and shuts down the server.
+func (s *Server) Close() {
+	s.websockets.close()
+	s.Server.Close()
+}
+
+// APIKey returns the API key the server accepts.
+func (s *Server) APIKey() string {
+	return s.apiKey
+}
+
+// WebsocketURL returns the URL of the websocket endpoint.
+func (s *Server) WebsocketURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + WebsocketPath
+}
+
+// Fail makes the endpoint at path fail with the fault. Faults are applied in the order they are added.
+func (s *Server) Fail(path string, fault Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults[path] = append(s.faults[path], &fault)
+}
+
+// RateLimit makes the next requests to the endpoint at path fail with 429 Too Many Requests.
+func (s *Server) RateLimit(path string, times int) {
+	s.Fail(path, Fault{StatusCode: http.StatusTooManyRequests, Message: MessageLimitReached, Times: times})
+}
+
+// Hits returns the number of requests made to the endpoint at path, including the failed ones.
+func (s *Server) Hits(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.hits[path]
+}
+
+// Reset removes all faults and clears the request counts.
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = make(map[string][]*Fault)
+	s.hits = make(map[string]int)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	fault, ok := s.record(r.URL.Path)
+	if !sleep(r, s.latency) {
+		return
+	}
+	if ok {
+		if !sleep(r, fault.Latency) {
+			return
+		}
+		if fault.StatusCode != 0 {
+			if fault.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(fault.RetryAfter.Seconds())))
+			}
+			writeError(w, fault.StatusCode, fault.Message)
+			return
+		}
+	}
+
+	if r.URL.Path == WebsocketPath {
+		s.websockets.serve(w, r)
+		return
+	}
+	if r.URL.Query().Get("apikey") != s.apiKey {
+		writeError(w, http.StatusUnauthorized, MessageInvalidAPIKey)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "")
+		return
+	}
+	if r.URL.Path == "/stable/profile-bulk" {
+		s.serveCSVPart(w, r)
+		return
+	}
+	s.serveJSON(w, r)
+}
+
+// record counts the request and returns the fault to apply to it, if any.
+func (s *Server) record(path string) (Fault, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.hits[path]++
+
+	faults := s.faults[path]
+	if len(faults) == 0 {
+		return Fault{}, false
+	}
+	fault := faults[0]
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times == 0 {
+			s.faults[path] = faults[1:]
+		}
+	}
+	return *fault, true
+}
+
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request) {
+	b, err := fs.ReadFile(s.fixtures, strings.TrimPrefix(path.Clean(r.URL.Path), "/")+".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusNotFound, "")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var items []map[string]json.RawMessage
+	if json.Unmarshal(b, &items) == nil {
+		items, err = paginate(filter(items, r.URL.Query()), r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if b, err = json.Marshal(items); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// serveCSVPart serves a part of a bulk endpoint. FMP responds with 400 Bad Request once there are no more parts.
+func (s *Server) serveCSVPart(w http.ResponseWriter, r *http.Request) {
+	part, err := strconv.Atoi(r.URL.Query().Get("part"))
+	if err != nil || part < 0 {
+		writeError(w, http.StatusBadRequest, "Invalid part parameter")
+		return
+	}
+	b, err := fs.ReadFile(s.fixtures, fmt.Sprintf("%s/%d.csv", strings.TrimPrefix(r.URL.Path, "/"), part))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "No more data")
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	_, _ = w.Write(b)
+}
+
+// filter keeps the items matching the query params. Items without the filtered field are kept as is.
+func filter(items []map[string]json.RawMessage, query map[string][]string) []map[string]json.RawMessage {
+	symbols := make(map[string]bool)
+	for _, key := range []string{"symbol", "symbols"} {
+		for _, v := range query[key] {
+			for _, symbol := range strings.Split(v, ",") {
+				if symbol = strings.TrimSpace(symbol); symbol != "" {
+					symbols[strings.ToUpper(symbol)] = true
+				}
+			}
+		}
+	}
+	exchange := strings.ToUpper(first(query["exchange"]))
+	from, to := first(query["from"]), first(query["to"])
+
+	filtered := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		if symbol, ok := stringField(item, "symbol"); ok && len(symbols) > 0 && !symbols[strings.ToUpper(symbol)] {
+			continue
+		}
+		if e, ok := stringField(item, "exchange"); ok && exchange != "" && strings.ToUpper(e) != exchange {
+			continue
+		}
+		if date, ok := dateField(item); ok && ((from != "" && date < from) || (to != "" && date > to)) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+// paginate returns the page of items selected by the page and limit query params. Pages start at 0.
+func paginate(items []map[string]json.RawMessage, query map[string][]string) ([]map[string]json.RawMessage, error) {
+	rawPage, rawLimit := first(query["page"]), first(query["limit"])
+	if rawPage == "" && rawLimit == "" {
+		return items, nil
+	}
+	page, size := 0, DefaultPageSize
+	var err error
+	if rawPage != "" {
+		if page, err = strconv.Atoi(rawPage); err != nil || page < 0 {
+			return nil, fmt.Errorf("invalid page: %s", rawPage)
+		}
+	}
+	if rawLimit != "" {
+		if size, err = strconv.Atoi(rawLimit); err != nil || size < 1 {
+			return nil, fmt.Errorf("invalid limit: %s", rawLimit)
+		}
+	}
+	start := min(page*size, len(items))
+	return items[start:min(start+size, len(items))], nil
+}
+
+func stringField(item map[string]json.RawMessage, key string) (string, bool) {
+	raw, ok := item[key]
+	if !ok {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// dateField returns the date of the item in the YYYY-MM-DD format.
+func dateField(item map[string]json.RawMessage) (string, bool) {
+	for _, key := range []string{"date", "publishedDate"} {
+		if s, ok := stringField(item, key); ok && len(s) >= len(time.DateOnly) {
+			return s[:len(time.DateOnly)], true
+		}
+	}
+	return "", false
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// sleep waits for the duration and reports whether the request is still alive.
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.Context().Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// writeError writes an error response the way FMP does.
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	b, _ := json.Marshal(map[string]string{"Error Message": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(b)
+}
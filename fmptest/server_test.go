@@ -0,0 +1,258 @@
+package fmptest_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/client/rest"
+	fmperrors "go.tradeforge.dev/fmp/errors"
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/market"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func newTestClient(t *testing.T, server *fmptest.Server, opts ...rest.Option) *market.HTTPClient {
+	t.Helper()
+	opts = append([]rest.Option{rest.WithRetryPolicy(rest.RetryPolicy{
+		MaxRetries:      2,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     10 * time.Millisecond,
+		Multiplier:      2,
+	})}, opts...)
+	return market.NewHTTPClient(
+		market.HTTPClientConfig{APIKey: server.APIKey(), BaseURL: server.URL},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		opts...,
+	)
+}
+
+func get(t *testing.T, server *fmptest.Server, path string) (int, []byte) {
+	t.Helper()
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	res, err := http.Get(server.URL + path + sep + "apikey=" + server.APIKey()) //nolint:noctx // test request
+	require.NoError(t, err)
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, b
+}
+
+func TestServer_ServesAllEndpoints(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+
+	paths := []string{
+		market.GetQuotePath,
+		market.GetPriceChangePath,
+		market.BatchGetQuotesPath,
+		market.BatchGetQuotesByExchangePath,
+		strings.Replace(market.GetHistoricalBarsPath, ":timeframe", string(model.Timeframe1Min), 1),
+		strings.Replace(market.GetHistoricalBarsPath, ":timeframe", string(model.Timeframe5Min), 1),
+		strings.Replace(market.GetHistoricalBarsPath, ":timeframe", string(model.Timeframe15Min), 1),
+		strings.Replace(market.GetHistoricalBarsPath, ":timeframe", string(model.Timeframe30Min), 1),
+		strings.Replace(market.GetHistoricalBarsPath, ":timeframe", string(model.Timeframe1Hour), 1),
+		strings.Replace(market.GetHistoricalBarsPath, ":timeframe", string(model.Timeframe4Hour), 1),
+		market.GetHistoricalPricesEODPath,
+		market.GetHistoricalMarketCapPath,
+		market.GetBulkPriceEODPath,
+		market.GetMostActiveTickersPath,
+		market.GetGainersPath,
+		market.GetLosersPath,
+		market.GetCompanyProfilePath,
+		market.BulkGetCompanyProfilePath + "?part=0",
+		market.GetFinancialKeyMetricsTTMPath,
+		market.GetFinancialRatiosTTMPath,
+		market.GetIncomeStatements,
+		market.GetBalanceSheets,
+		market.GetCashFlowStatements,
+		market.GetSP500IndexConstituentsPath,
+		market.GetNasdaqIndexConstituentsPath,
+		market.GetDowJonesIndexConstituentsPath,
+		market.GetAvailableExchangesPath,
+		market.GetFMPArticlesPath,
+		market.GetLatestGeneralNewsPath,
+		market.GetPressReleasesPath,
+		market.GetLatestStockNewsPath,
+		market.GetStockNewsPath,
+		market.GetLatestCryptoNewsPath,
+		market.GetCryptoNewsPath,
+		market.GetLatestForexNewsPath,
+		market.GetForexNewsPath,
+		market.GetHouseFinancialDisclosuresPath,
+		market.GetEarningsCalendarPath,
+		market.GetDividendsCalendarPath,
+		market.GetInsiderTradesPath,
+		market.GetIndexListPath,
+		market.BatchGetIndexQuotesPath,
+		market.GetHistoricalSP500Path,
+		market.GetHistoricalNasdaqPath,
+		market.GetHistoricalDowJonesPath,
+		market.GetAdvancedDCFPath,
+		market.GetAllExchangesTradingHoursPath,
+		market.GetExchangeHolidaysPath,
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			status, body := get(t, server, path)
+			assert.Equal(t, http.StatusOK, status)
+			assert.NotEmpty(t, body)
+		})
+	}
+}
+
+func TestServer_Filtering(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantLen int
+	}{
+		{name: "success:symbol", path: market.GetQuotePath + "?symbol=MSFT", wantLen: 1},
+		{name: "success:symbols", path: market.BatchGetQuotesPath + "?symbols=AAPL,MSFT,UNKNOWN", wantLen: 2},
+		{name: "success:unknown symbol", path: market.GetQuotePath + "?symbol=UNKNOWN", wantLen: 0},
+		{name: "success:date range", path: market.GetHistoricalPricesEODPath + "?symbol=AAPL&from=2025-01-02&to=2025-01-10", wantLen: 6},
+		{name: "success:limit", path: market.GetIncomeStatements + "?symbol=AAPL&limit=3", wantLen: 3},
+		{name: "success:page", path: market.GetHouseFinancialDisclosuresPath + "?page=1&limit=25", wantLen: 15},
+		{name: "success:page beyond last", path: market.GetHouseFinancialDisclosuresPath + "?page=2&limit=25", wantLen: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(t, server, tt.path)
+			require.Equal(t, http.StatusOK, status)
+
+			var items []json.RawMessage
+			require.NoError(t, json.Unmarshal(body, &items))
+			assert.Len(t, items, tt.wantLen)
+		})
+	}
+}
+
+func TestServer_BulkParts(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	total := 0
+	for part := 0; ; part++ {
+		res, err := client.BulkGetCompanyProfile(ctx, &model.BulkGetCompanyProfilesParams{Part: part})
+		require.NoError(t, err)
+		if len(res) == 0 {
+			break
+		}
+		total += len(res)
+	}
+	assert.Equal(t, 13, total)
+}
+
+func TestServer_Faults(t *testing.T) {
+	ctx := context.Background()
+	params := &model.GetQuoteParams{Symbol: "AAPL"}
+
+	t.Run("success:retried after rate limit", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		server.RateLimit(market.GetQuotePath, 2)
+
+		res, err := newTestClient(t, server).GetQuote(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, "AAPL", res.Symbol)
+		assert.Equal(t, 3, server.Hits(market.GetQuotePath))
+	})
+	t.Run("error:rate limited", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		server.RateLimit(market.GetQuotePath, 0)
+
+		_, err := newTestClient(t, server).GetQuote(ctx, params)
+		require.ErrorIs(t, err, fmperrors.ErrRateLimited)
+		assert.Equal(t, 3, server.Hits(market.GetQuotePath))
+	})
+	t.Run("error:restricted endpoint", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		server.Fail(market.GetQuotePath, fmptest.Fault{StatusCode: http.StatusPaymentRequired, Message: fmptest.MessageRestrictedEndpoint})
+
+		_, err := newTestClient(t, server).GetQuote(ctx, params)
+		require.ErrorIs(t, err, fmperrors.ErrPremiumEndpoint)
+		assert.Equal(t, 1, server.Hits(market.GetQuotePath))
+	})
+	t.Run("error:invalid api key", func(t *testing.T) {
+		server := fmptest.NewServer(fmptest.WithAPIKey("another-api-key"))
+		defer server.Close()
+		client := market.NewHTTPClient(
+			market.HTTPClientConfig{APIKey: "test-api-key", BaseURL: server.URL},
+			slog.New(slog.NewTextHandler(io.Discard, nil)),
+		)
+
+		_, err := client.GetQuote(ctx, params)
+		require.ErrorIs(t, err, fmperrors.ErrUnauthorized)
+	})
+	t.Run("error:latency", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		server.Fail(market.GetQuotePath, fmptest.Fault{Latency: time.Second})
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := newTestClient(t, server).GetQuote(ctx, params)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestServer_Websocket(t *testing.T) {
+	server := fmptest.NewServer(fmptest.WithHeartbeatInterval(10 * time.Millisecond))
+	defer server.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("success:quotes", func(t *testing.T) {
+		client, err := market.NewWebsocketClient(context.Background(), market.WebsocketClientConfig{APIKey: server.APIKey()}, logger)
+		require.NoError(t, err)
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		require.NoError(t, client.Subscribe(context.Background(), []string{"AAPL"}))
+		assert.Equal(t, 1, server.Subscribers("AAPL"))
+
+		want := model.WebsocketQuote{
+			Symbol:      "aapl",
+			AskPrice:    decimal.RequireFromString("243.9"),
+			AskSize:     decimal.NewFromInt(3),
+			BidPrice:    decimal.RequireFromString("243.8"),
+			BidSize:     decimal.NewFromInt(1),
+			LastPrice:   decimal.RequireFromString("243.85"),
+			LastUpdated: time.Now().UnixMilli(),
+		}
+		server.PublishQuote(model.WebsocketQuote{Symbol: "msft", LastUpdated: want.LastUpdated})
+		server.PublishQuote(want)
+
+		select {
+		case got := <-client.Quotes():
+			assert.Equal(t, want.Symbol, got.Symbol)
+			assert.True(t, want.LastPrice.Equal(got.LastPrice))
+			assert.Equal(t, want.LastUpdated, got.LastUpdated)
+		case <-time.After(time.Second):
+			t.Fatal("no quote received")
+		}
+	})
+	t.Run("error:invalid api key", func(t *testing.T) {
+		client, err := market.NewWebsocketClient(context.Background(), market.WebsocketClientConfig{APIKey: "invalid"}, logger)
+		require.NoError(t, err)
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		assert.Error(t, client.Connect(server.WebsocketURL()))
+	})
+}
@@ -0,0 +1,281 @@
+package fmptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+// websocketEvent is a control message sent by the websocket endpoint.
+type websocketEvent struct {
+	Event     model.WebsocketEventName `json:"event"`
+	Status    int                      `json:"status"`
+	Message   string                   `json:"message,omitempty"`
+	Timestamp int64                    `json:"timestamp,omitempty"`
+}
+
+// websocketQuote is a quote message as sent by the websocket endpoint.
+type websocketQuote struct {
+	Type model.WebsocketMessageType `json:"type"`
+	model.WebsocketQuote
+}
+
+// websocketTrade is a trade message as sent by the websocket endpoint.
+type websocketTrade struct {
+	Type model.WebsocketMessageType `json:"type"`
+	model.WebsocketTrade
+}
+
+// websocketRequest is a message sent by a websocket client.
+type websocketRequest struct {
+	Event model.WebsocketEventName `json:"event"`
+	Data  struct {
+		APIKey  string   `json:"apiKey"`
+		Tickers []string `json:"ticker"`
+	} `json:"data"`
+}
+
+// websocketHub serves the websocket endpoint. It speaks the login, subscribe and unsubscribe protocol of FMP,
+// sends heartbeats and publishes quotes to the subscribed connections.
+type websocketHub struct {
+	apiKey            string
+	heartbeatInterval time.Duration
+	maxSymbols        int
+	maxSubscriptions  int
+	upgrader          websocket.Upgrader
+
+	lock  sync.Mutex
+	conns map[*websocketConn]struct{}
+}
+
+type websocketConn struct {
+	conn *websocket.Conn
+	done chan struct{}
+
+	writeLock sync.Mutex
+	paused    atomic.Bool
+
+	lock          sync.Mutex
+	authenticated bool
+	symbols       map[string]struct{}
+}
+
+func newWebsocketHub(apiKey string, heartbeatInterval time.Duration, maxSymbols, maxSubscriptions int) *websocketHub {
+	return &websocketHub{
+		apiKey:            apiKey,
+		heartbeatInterval: heartbeatInterval,
+		maxSymbols:        maxSymbols,
+		maxSubscriptions:  maxSubscriptions,
+		conns:             make(map[*websocketConn]struct{}),
+	}
+}
+
+// PublishQuote sends the quote to all connections subscribed to its symbol.
+func (s *Server) PublishQuote(quote model.WebsocketQuote) {
+	msg := websocketQuote{Type: model.WebsocketMessageTypeQuote, WebsocketQuote: quote}
+	for _, c := range s.websockets.connections() {
+		if c.subscribed(quote.Symbol) {
+			_ = c.write(msg)
+		}
+	}
+}
+
+// PublishTrade sends the trade to all connections subscribed to its symbol.
+func (s *Server) PublishTrade(trade model.WebsocketTrade) {
+	msg := websocketTrade{Type: model.WebsocketMessageTypeTrade, WebsocketTrade: trade}
+	for _, c := range s.websockets.connections() {
+		if c.subscribed(trade.Symbol) {
+			_ = c.write(msg)
+		}
+	}
+}
+
+// Broadcast sends the message as JSON to all authenticated connections.
+func (s *Server) Broadcast(msg any) {
+	for _, c := range s.websockets.connections() {
+		if c.isAuthenticated() {
+			_ = c.write(msg)
+		}
+	}
+}
+
+// Subscribers returns the number of connections subscribed to the symbol.
+func (s *Server) Subscribers(symbol string) int {
+	n := 0
+	for _, c := range s.websockets.connections() {
+		if c.subscribed(symbol) {
+			n++
+		}
+	}
+	return n
+}
+
+// DropWebsockets closes all open websocket connections as if the network dropped them. The endpoint keeps accepting
+// new connections.
+func (s *Server) DropWebsockets() {
+	s.websockets.close()
+}
+
+// PauseWebsockets silences the open websocket connections while keeping them open, as if the feed stalled.
+// Heartbeats, acknowledgments and quotes are discarded until ResumeWebsockets is called. New connections are not paused.
+func (s *Server) PauseWebsockets() {
+	for _, c := range s.websockets.connections() {
+		c.paused.Store(true)
+	}
+}
+
+// ResumeWebsockets resumes the output of the paused websocket connections.
+func (s *Server) ResumeWebsockets() {
+	for _, c := range s.websockets.connections() {
+		c.paused.Store(false)
+	}
+}
+
+func (h *websocketHub) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &websocketConn{
+		conn:    conn,
+		done:    make(chan struct{}),
+		symbols: make(map[string]struct{}),
+	}
+	h.lock.Lock()
+	h.conns[c] = struct{}{}
+	h.lock.Unlock()
+	defer func() {
+		h.lock.Lock()
+		delete(h.conns, c)
+		h.lock.Unlock()
+		close(c.done)
+		_ = conn.Close()
+	}()
+
+	if h.heartbeatInterval > 0 {
+		go c.sendHeartbeats(h.heartbeatInterval)
+	}
+	for {
+		var req websocketRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if err := c.write(h.handle(c, req)); err != nil {
+			return
+		}
+	}
+}
+
+// handle applies the request to the connection and returns the acknowledgment to send back.
+func (h *websocketHub) handle(c *websocketConn, req websocketRequest) websocketEvent {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch req.Event {
+	case model.WebsocketEventNameLogin:
+		if req.Data.APIKey != h.apiKey {
+			return websocketEvent{Event: req.Event, Status: http.StatusUnauthorized, Message: "Invalid API KEY"}
+		}
+		c.authenticated = true
+		return websocketEvent{Event: req.Event, Status: http.StatusOK, Message: "Authenticated"}
+	case model.WebsocketEventNameSubscribe, model.WebsocketEventNameUnsubscribe:
+		if !c.authenticated {
+			return websocketEvent{Event: req.Event, Status: http.StatusUnauthorized, Message: "Not authenticated"}
+		}
+		if h.maxSymbols > 0 && len(req.Data.Tickers) > h.maxSymbols {
+			return websocketEvent{Event: req.Event, Status: http.StatusBadRequest, Message: "Too many tickers"}
+		}
+		if req.Event == model.WebsocketEventNameSubscribe && h.maxSubscriptions > 0 && c.subscriptionsWith(req.Data.Tickers) > h.maxSubscriptions {
+			return websocketEvent{Event: req.Event, Status: http.StatusForbidden, Message: "Subscription limit reached"}
+		}
+		for _, symbol := range req.Data.Tickers {
+			if req.Event == model.WebsocketEventNameSubscribe {
+				c.symbols[strings.ToLower(symbol)] = struct{}{}
+			} else {
+				delete(c.symbols, strings.ToLower(symbol))
+			}
+		}
+		return websocketEvent{Event: req.Event, Status: http.StatusOK, Message: string(req.Event) + "d to " + strings.Join(req.Data.Tickers, ",")}
+	}
+	return websocketEvent{Event: req.Event, Status: http.StatusBadRequest, Message: "Unknown event"}
+}
+
+func (h *websocketHub) connections() []*websocketConn {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	conns := make([]*websocketConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func (h *websocketHub) close() {
+	for _, c := range h.connections() {
+		_ = c.conn.Close()
+	}
+}
+
+func (c *websocketConn) sendHeartbeats(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case t := <-ticker.C:
+			if err := c.write(websocketEvent{
+				Event:     model.WebsocketEventNameHeartbeat,
+				Status:    http.StatusOK,
+				Timestamp: t.UnixMilli(),
+			}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *websocketConn) write(msg any) error {
+	if c.paused.Load() {
+		return nil
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// subscriptionsWith returns the number of symbols the connection would be subscribed to with the symbols.
+// The lock of the connection must be held.
+func (c *websocketConn) subscriptionsWith(symbols []string) int {
+	n := len(c.symbols)
+	for _, symbol := range symbols {
+		if _, ok := c.symbols[strings.ToLower(symbol)]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *websocketConn) subscribed(symbol string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, ok := c.symbols[strings.ToLower(symbol)]
+	return ok
+}
+
+func (c *websocketConn) isAuthenticated() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.authenticated
+}
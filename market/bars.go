@@ -0,0 +1,324 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.tradeforge.dev/fmp/model"
+	"go.tradeforge.dev/fmp/pkg/types"
+)
+
+const (
+	// DefaultBarTimeZone is the time zone of the bars unless configured otherwise, the one of the US exchanges.
+	DefaultBarTimeZone = "America/New_York"
+
+	// DefaultBarSessionOpen is the time of day the bars are aligned to unless configured otherwise, the opening of
+	// the US exchanges. FMP aligns its hourly bars to it as well.
+	DefaultBarSessionOpen = 9*time.Hour + 30*time.Minute
+)
+
+// LiveBar is a bar of a symbol built from the quotes of the websocket.
+type LiveBar struct {
+	// Symbol is the symbol of the bar, in lower case.
+	Symbol    string
+	Timeframe model.Timeframe
+
+	// Start is the time the bar opens, in the time zone of the bars. The DateTime of the bar holds the same time
+	// on the wall clock of the exchange, like the historical bars of FMP do.
+	Start time.Time
+	Bar   model.Bar
+}
+
+type barConfig struct {
+	timeZone    string
+	sessionOpen time.Duration
+	closeDelay  time.Duration
+}
+
+// BarOption changes how a BarBuilder buckets the quotes.
+type BarOption func(c *barConfig)
+
+// WithBarTradingHours aligns the bars to the opening of the exchange in its time zone, e.g. one of the
+// GetAllExchangesTradingHours results. The bars of the US exchanges are built by default.
+func WithBarTradingHours(hours model.ExchangeTradingHours) BarOption {
+	return func(c *barConfig) {
+		if hours.TimeZone != "" {
+			c.timeZone = hours.TimeZone
+		}
+		if hours.OpeningHour != "" {
+			c.sessionOpen = hours.OpeningHour.Duration()
+		}
+	}
+}
+
+// WithBarCloseDelay waits for the delay after the end of a bar before closing it, so that the quotes sent just
+// before the end but received just after are still part of it. Bars close right at their end by default.
+func WithBarCloseDelay(delay time.Duration) BarOption {
+	return func(c *barConfig) {
+		c.closeDelay = delay
+	}
+}
+
+// BarBuilder builds the bars of a timeframe from the last prices and sizes of the quotes of the websocket.
+// It is safe for concurrent use.
+//
+// The bars are aligned to the opening of the exchange in its time zone, so that they match the historical bars of
+// FMP and a chart can continue from them. Quotes without a last price and quotes of bars already closed are ignored.
+//
+// The volume of a bar is the sum of the sizes of its trades. As the quotes repeat the last trade on every bid and ask
+// update, a quote only adds its last size if its last price or size differs from the previous quote of its symbol.
+type BarBuilder struct {
+	timeframe   model.Timeframe
+	duration    time.Duration
+	location    *time.Location
+	sessionOpen time.Duration
+	closeDelay  time.Duration
+	now         func() time.Time
+
+	lock sync.Mutex
+	// open holds the bar being built of each symbol.
+	open map[string]*LiveBar
+	// closed holds the start of the last bar closed of each symbol.
+	closed map[string]time.Time
+	// trades holds the last trade of each symbol.
+	trades map[string]barTrade
+}
+
+// barTrade is the last trade of a quote.
+type barTrade struct {
+	price decimal.Decimal
+	size  decimal.Decimal
+}
+
+// NewBarBuilder returns a builder of the bars of the timeframe.
+func NewBarBuilder(timeframe model.Timeframe, opts ...BarOption) (*BarBuilder, error) {
+	duration := timeframe.Duration()
+	if duration <= 0 {
+		return nil, fmt.Errorf("unsupported timeframe: %q", timeframe)
+	}
+	config := barConfig{timeZone: DefaultBarTimeZone, sessionOpen: DefaultBarSessionOpen}
+	for _, o := range opts {
+		o(&config)
+	}
+	location, err := time.LoadLocation(config.timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("loading location: %w", err)
+	}
+	return &BarBuilder{
+		timeframe:   timeframe,
+		duration:    duration,
+		location:    location,
+		sessionOpen: config.sessionOpen,
+		closeDelay:  config.closeDelay,
+		now:         time.Now,
+		open:        make(map[string]*LiveBar),
+		closed:      make(map[string]time.Time),
+		trades:      make(map[string]barTrade),
+	}, nil
+}
+
+// Add updates the bar of the symbol of the quote. It returns the previous bar of the symbol if the quote is past its
+// end, which closes it, and no bar otherwise.
+func (b *BarBuilder) Add(quote model.WebsocketQuote) []LiveBar {
+	price := quote.LastPrice
+	if !price.IsPositive() {
+		return nil
+	}
+	symbol := strings.ToLower(quote.Symbol)
+	start := b.bucket(quote.Time())
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if closed, ok := b.closed[symbol]; ok && !start.After(closed) {
+		return nil
+	}
+	var bars []LiveBar
+	current := b.open[symbol]
+	if current != nil {
+		if start.Before(current.Start) {
+			return nil
+		}
+		if start.After(current.Start) {
+			bars = append(bars, b.close(symbol))
+			current = nil
+		}
+	}
+	if current == nil {
+		current = b.newBar(symbol, start, price)
+		b.open[symbol] = current
+	}
+	current.Bar.High = decimal.Max(current.Bar.High, price)
+	current.Bar.Low = decimal.Min(current.Bar.Low, price)
+	current.Bar.Close = price
+
+	trade := barTrade{price: price, size: quote.LastSize}
+	if previous, ok := b.trades[symbol]; !ok || !previous.price.Equal(trade.price) || !previous.size.Equal(trade.size) {
+		current.Bar.Volume = current.Bar.Volume.Add(trade.size)
+	}
+	b.trades[symbol] = trade
+	return bars
+}
+
+// Flush closes and returns the bars that ended before now, ordered by symbol, so that bars close even if their
+// symbol has no further quote.
+func (b *BarBuilder) Flush(now time.Time) []LiveBar {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var bars []LiveBar
+	for _, symbol := range slices.Sorted(maps.Keys(b.open)) {
+		if !b.open[symbol].Start.Add(b.duration).After(now) {
+			bars = append(bars, b.close(symbol))
+		}
+	}
+	return bars
+}
+
+// Current returns the bar being built of the symbol, if any.
+func (b *BarBuilder) Current(symbol string) (LiveBar, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	current, ok := b.open[strings.ToLower(symbol)]
+	if !ok {
+		return LiveBar{}, false
+	}
+	return *current, true
+}
+
+// Backfill fetches the historical bars of the symbol since the time and returns the closed ones, from the oldest
+// to the newest. The bar still open, if any, becomes the bar being built, so that the quotes added afterwards
+// continue it. It should be called on startup, before the first quote of the symbol: historical bars are ignored
+// for the periods the builder already has a bar of.
+//
+//	bars, err := builder.Backfill(ctx, &client.TickerClient, "AAPL", time.Now().Add(-24*time.Hour))
+//	...
+//	consumer := wss.Consume(market.WithConsumerSymbols("AAPL"))
+//	for bar := range builder.Run(ctx, consumer.Quotes()) {
+//		...
+//	}
+func (b *BarBuilder) Backfill(ctx context.Context, client *TickerClient, symbol string, since time.Time, opts ...model.RequestOption) ([]LiveBar, error) {
+	now := b.now()
+	params := model.GetHistoricalBarsParams{
+		Timeframe: b.timeframe,
+		Symbol:    strings.ToUpper(symbol),
+		Since:     types.DateFromTime(since.In(b.location)),
+		Until:     types.DateFromTime(now.In(b.location)),
+	}
+	history, err := FetchRange(ctx, client.GetHistoricalBars, params, WithRangeRequestOptions(opts...))
+	if err != nil {
+		return nil, fmt.Errorf("backfilling bars: %w", err)
+	}
+
+	symbol = strings.ToLower(symbol)
+	from := b.bucket(since)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var bars []LiveBar
+	// FMP returns the bars from the newest to the oldest.
+	for _, bar := range slices.Backward(history) {
+		start, err := time.ParseInLocation(time.DateTime, string(bar.DateTime), b.location)
+		if err != nil {
+			return nil, fmt.Errorf("parsing bar time: %w", err)
+		}
+		if start.Before(from) || b.known(symbol, start) {
+			continue
+		}
+		if start.Add(b.duration).After(now) {
+			b.open[symbol] = &LiveBar{Symbol: symbol, Timeframe: b.timeframe, Start: start, Bar: bar}
+			continue
+		}
+		b.closed[symbol] = start
+		bars = append(bars, LiveBar{Symbol: symbol, Timeframe: b.timeframe, Start: start, Bar: bar})
+	}
+	return bars, nil
+}
+
+// Run adds the quotes of the channel, e.g. the one of a QuoteConsumer, and sends the bars as they close until the
+// channel is closed or the context is done. The returned channel is closed then; the bars still open are not sent.
+func (b *BarBuilder) Run(ctx context.Context, quotes <-chan model.WebsocketQuote) <-chan LiveBar {
+	out := make(chan LiveBar, DefaultConsumerBuffer)
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(b.untilClose(b.now()))
+		defer timer.Stop()
+		for {
+			var bars []LiveBar
+			select {
+			case <-ctx.Done():
+				return
+			case quote, ok := <-quotes:
+				if !ok {
+					return
+				}
+				bars = b.Add(quote)
+			case <-timer.C:
+				now := b.now()
+				bars = b.Flush(now.Add(-b.closeDelay))
+				timer.Reset(b.untilClose(now))
+			}
+			for _, bar := range bars {
+				select {
+				case out <- bar:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// bucket returns the start of the bar of the time, aligned to the opening of the session of its day.
+func (b *BarBuilder) bucket(t time.Time) time.Time {
+	t = t.In(b.location)
+	year, month, day := t.Date()
+	// The opening is set on the wall clock rather than added to midnight, which daylight saving time would shift.
+	open := time.Date(year, month, day, 0, int(b.sessionOpen/time.Minute), 0, 0, b.location)
+	n := t.Sub(open) / b.duration
+	if t.Before(open) && t.Sub(open)%b.duration != 0 {
+		n--
+	}
+	return open.Add(n * b.duration)
+}
+
+// untilClose returns the time until the bar of now closes.
+func (b *BarBuilder) untilClose(now time.Time) time.Duration {
+	return b.bucket(now).Add(b.duration).Sub(now) + b.closeDelay
+}
+
+func (b *BarBuilder) newBar(symbol string, start time.Time, price decimal.Decimal) *LiveBar {
+	return &LiveBar{
+		Symbol:    symbol,
+		Timeframe: b.timeframe,
+		Start:     start,
+		Bar: model.Bar{
+			Open:     price,
+			High:     price,
+			Low:      price,
+			Close:    price,
+			DateTime: types.DateTimeFromTime(start),
+		},
+	}
+}
+
+// known reports whether the builder has a bar of the symbol starting at or after the time.
+func (b *BarBuilder) known(symbol string, start time.Time) bool {
+	if closed, ok := b.closed[symbol]; ok && !start.After(closed) {
+		return true
+	}
+	current, ok := b.open[symbol]
+	return ok && !start.Before(current.Start)
+}
+
+func (b *BarBuilder) close(symbol string) LiveBar {
+	bar := *b.open[symbol]
+	delete(b.open, symbol)
+	b.closed[symbol] = bar.Start
+	return bar
+}
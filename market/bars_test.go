@@ -0,0 +1,187 @@
+package market
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func newYork(t *testing.T) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(DefaultBarTimeZone)
+	require.NoError(t, err)
+	return location
+}
+
+func tradeQuote(symbol string, price, size float64, at time.Time) model.WebsocketQuote {
+	return model.WebsocketQuote{
+		Symbol:      symbol,
+		LastPrice:   decimal.NewFromFloat(price),
+		LastSize:    decimal.NewFromFloat(size),
+		LastUpdated: at.UnixMilli(),
+	}
+}
+
+func TestBarBuilder_Bucket(t *testing.T) {
+	location := newYork(t)
+	tests := []struct {
+		name      string
+		timeframe model.Timeframe
+		opts      []BarOption
+		time      time.Time
+		expected  time.Time
+	}{
+		{
+			name:      "success:minute",
+			timeframe: model.Timeframe5Min,
+			time:      time.Date(2025, 1, 2, 10, 7, 59, 0, location),
+			expected:  time.Date(2025, 1, 2, 10, 5, 0, 0, location),
+		},
+		{
+			name:      "success:hour aligned to the opening",
+			timeframe: model.Timeframe1Hour,
+			time:      time.Date(2025, 1, 2, 10, 15, 0, 0, location),
+			expected:  time.Date(2025, 1, 2, 9, 30, 0, 0, location),
+		},
+		{
+			name:      "success:before the opening",
+			timeframe: model.Timeframe4Hour,
+			time:      time.Date(2025, 1, 2, 9, 0, 0, 0, location),
+			expected:  time.Date(2025, 1, 2, 5, 30, 0, 0, location),
+		},
+		{
+			name:      "success:daylight saving time",
+			timeframe: model.Timeframe1Hour,
+			time:      time.Date(2025, 3, 10, 14, 45, 0, 0, time.UTC),
+			expected:  time.Date(2025, 3, 10, 10, 30, 0, 0, location),
+		},
+		{
+			name:      "success:exchange trading hours",
+			timeframe: model.Timeframe1Hour,
+			opts:      []BarOption{WithBarTradingHours(model.ExchangeTradingHours{TimeZone: "UTC", OpeningHour: "00:00:00"})},
+			time:      time.Date(2025, 1, 2, 10, 15, 0, 0, location),
+			expected:  time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder, err := NewBarBuilder(tt.timeframe, tt.opts...)
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(builder.bucket(tt.time)), "got %s", builder.bucket(tt.time))
+		})
+	}
+
+	_, err := NewBarBuilder("1day")
+	assert.ErrorContains(t, err, "unsupported timeframe")
+	_, err = NewBarBuilder(model.Timeframe1Min, WithBarTradingHours(model.ExchangeTradingHours{TimeZone: "Nowhere/Land"}))
+	assert.ErrorContains(t, err, "loading location")
+}
+
+func TestBarBuilder_Add(t *testing.T) {
+	location := newYork(t)
+	open := time.Date(2025, 1, 2, 9, 30, 0, 0, location)
+	builder, err := NewBarBuilder(model.Timeframe1Min)
+	require.NoError(t, err)
+
+	assert.Empty(t, builder.Add(tradeQuote("AAPL", 243, 100, open.Add(time.Second))))
+	assert.Empty(t, builder.Add(tradeQuote("aapl", 245, 50, open.Add(20*time.Second))))
+	assert.Empty(t, builder.Add(tradeQuote("aapl", 0, 0, open.Add(30*time.Second))), "quotes without a last price are ignored")
+	assert.Empty(t, builder.Add(tradeQuote("aapl", 242, 25, open.Add(40*time.Second))))
+	update := tradeQuote("aapl", 242, 25, open.Add(45*time.Second))
+	update.BidPrice = decimal.NewFromFloat(241.9)
+	assert.Empty(t, builder.Add(update), "bid and ask updates repeat the last trade")
+	assert.Empty(t, builder.Add(tradeQuote("msft", 420, 10, open.Add(50*time.Second))))
+
+	bars := builder.Add(tradeQuote("aapl", 244, 10, open.Add(time.Minute)))
+	require.Len(t, bars, 1)
+	bar := bars[0]
+	assert.Equal(t, "aapl", bar.Symbol)
+	assert.Equal(t, model.Timeframe1Min, bar.Timeframe)
+	assert.True(t, open.Equal(bar.Start))
+	assert.Equal(t, "2025-01-02 09:30:00", string(bar.Bar.DateTime))
+	assert.Equal(t, "243", bar.Bar.Open.String())
+	assert.Equal(t, "245", bar.Bar.High.String())
+	assert.Equal(t, "242", bar.Bar.Low.String())
+	assert.Equal(t, "242", bar.Bar.Close.String())
+	assert.Equal(t, "175", bar.Bar.Volume.String())
+
+	assert.Empty(t, builder.Add(tradeQuote("aapl", 300, 10, open.Add(59*time.Second))), "quotes of closed bars are ignored")
+	current, ok := builder.Current("AAPL")
+	require.True(t, ok)
+	assert.Equal(t, "244", current.Bar.High.String())
+
+	assert.Empty(t, builder.Flush(open.Add(time.Minute).Add(-time.Nanosecond)))
+	bars = builder.Flush(open.Add(2 * time.Minute))
+	require.Len(t, bars, 2)
+	assert.Equal(t, "aapl", bars[0].Symbol)
+	assert.Equal(t, "msft", bars[1].Symbol)
+	_, ok = builder.Current("aapl")
+	assert.False(t, ok)
+}
+
+func TestBarBuilder_Backfill(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+	client := newFixtureTestClient(server)
+	location := newYork(t)
+
+	builder, err := NewBarBuilder(model.Timeframe5Min)
+	require.NoError(t, err)
+	builder.now = func() time.Time { return time.Date(2025, 1, 2, 15, 57, 0, 0, location) }
+
+	bars, err := builder.Backfill(context.Background(), &client.TickerClient, "aapl", time.Date(2025, 1, 2, 9, 0, 0, 0, location))
+	require.NoError(t, err)
+	require.Len(t, bars, 77)
+	assert.Equal(t, "2025-01-02 09:30:00", string(bars[0].Bar.DateTime))
+	assert.True(t, time.Date(2025, 1, 2, 9, 30, 0, 0, location).Equal(bars[0].Start))
+	assert.Equal(t, "2025-01-02 15:50:00", string(bars[len(bars)-1].Bar.DateTime))
+
+	t.Run("success:live quotes continue the open bar", func(t *testing.T) {
+		assert.Empty(t, builder.Add(tradeQuote("AAPL", 240, 10, time.Date(2025, 1, 2, 15, 52, 0, 0, location))))
+		assert.Empty(t, builder.Add(tradeQuote("AAPL", 252, 10, time.Date(2025, 1, 2, 15, 58, 0, 0, location))))
+		bars := builder.Add(tradeQuote("AAPL", 251, 10, time.Date(2025, 1, 2, 16, 0, 0, 0, location)))
+		require.Len(t, bars, 1)
+		assert.Equal(t, "2025-01-02 15:55:00", string(bars[0].Bar.DateTime))
+		assert.Equal(t, "250.78", bars[0].Bar.Open.String())
+		assert.Equal(t, "252", bars[0].Bar.High.String())
+		assert.Equal(t, "249.82", bars[0].Bar.Low.String())
+		assert.Equal(t, "252", bars[0].Bar.Close.String())
+		assert.Equal(t, "2881928", bars[0].Bar.Volume.String())
+	})
+	t.Run("error:failed call", func(t *testing.T) {
+		server.Fail("/stable/historical-chart/5min", fmptest.Fault{StatusCode: http.StatusInternalServerError})
+		_, err := builder.Backfill(context.Background(), &client.TickerClient, "msft", time.Date(2025, 1, 2, 9, 0, 0, 0, location))
+		assert.ErrorContains(t, err, "backfilling bars")
+	})
+}
+
+func TestBarBuilder_Run(t *testing.T) {
+	location := newYork(t)
+	open := time.Date(2025, 1, 2, 9, 30, 0, 0, location)
+	builder, err := NewBarBuilder(model.Timeframe1Min)
+	require.NoError(t, err)
+	// The clock runs from just before the end of the bar of 09:31, so that it closes without waiting for a minute.
+	started := time.Now()
+	builder.now = func() time.Time { return open.Add(2*time.Minute - 200*time.Millisecond).Add(time.Since(started)) }
+
+	quotes := make(chan model.WebsocketQuote)
+	bars := builder.Run(context.Background(), quotes)
+	quotes <- tradeQuote("aapl", 243, 100, open)
+	quotes <- tradeQuote("aapl", 244, 100, open.Add(time.Minute))
+	bar := <-bars
+	assert.Equal(t, "2025-01-02 09:30:00", string(bar.Bar.DateTime))
+
+	bar = <-bars
+	assert.Equal(t, "2025-01-02 09:31:00", string(bar.Bar.DateTime))
+	close(quotes)
+	_, ok := <-bars
+	assert.False(t, ok)
+}
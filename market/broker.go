@@ -0,0 +1,278 @@
+package market
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+// DefaultConsumerBuffer is the number of quotes buffered per consumer unless configured otherwise.
+const DefaultConsumerBuffer = 256
+
+// Backpressure is what a consumer does with a quote once its buffer is full.
+type Backpressure string
+
+const (
+	// BackpressureBlock waits for the consumer to make room. It stalls the publisher, and with it the other consumers.
+	BackpressureBlock Backpressure = "block"
+
+	// BackpressureDropOldest drops the oldest buffered quote to make room.
+	BackpressureDropOldest Backpressure = "drop-oldest"
+
+	// BackpressureConflate keeps only the latest quote of each symbol in the buffer, in place of the previous one.
+	// The oldest quote is dropped if the buffer is full of quotes of other symbols.
+	BackpressureConflate Backpressure = "conflate"
+)
+
+type consumerConfig struct {
+	buffer       int
+	backpressure Backpressure
+	symbols      []string
+}
+
+// ConsumerOption changes how a consumer receives quotes from the broker.
+type ConsumerOption func(c *consumerConfig)
+
+// WithConsumerBuffer sets the number of quotes buffered for the consumer. It defaults to DefaultConsumerBuffer.
+func WithConsumerBuffer(n int) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.buffer = n
+	}
+}
+
+// WithBackpressure sets what the consumer does with a quote once its buffer is full. It defaults to BackpressureBlock.
+func WithBackpressure(backpressure Backpressure) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.backpressure = backpressure
+	}
+}
+
+// WithConsumerSymbols restricts the consumer to the quotes of the symbols. Symbols are case-insensitive.
+// The consumer receives the quotes of all symbols if none is set.
+func WithConsumerSymbols(symbols ...string) ConsumerOption {
+	return func(c *consumerConfig) {
+		c.symbols = append(c.symbols, symbols...)
+	}
+}
+
+// QuoteBroker fans quotes out to consumers, each with its own buffer and backpressure policy, so that a slow
+// consumer does not hold up the others unless it asked to.
+type QuoteBroker struct {
+	lock      sync.RWMutex
+	consumers map[*QuoteConsumer]struct{}
+
+	dropped atomic.Uint64
+	onDrop  func(quote model.WebsocketQuote)
+}
+
+// NewQuoteBroker returns a broker without consumers.
+func NewQuoteBroker() *QuoteBroker {
+	return &QuoteBroker{consumers: make(map[*QuoteConsumer]struct{})}
+}
+
+// Consume adds a consumer of the quotes published from now on. The caller should call Close on the consumer once done.
+func (b *QuoteBroker) Consume(opts ...ConsumerOption) *QuoteConsumer {
+	config := consumerConfig{buffer: DefaultConsumerBuffer, backpressure: BackpressureBlock}
+	for _, o := range opts {
+		o(&config)
+	}
+	c := &QuoteConsumer{
+		broker:       b,
+		backpressure: config.backpressure,
+		size:         max(config.buffer, 1),
+		out:          make(chan model.WebsocketQuote),
+		notify:       make(chan struct{}, 1),
+		space:        make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	if len(config.symbols) > 0 {
+		c.symbols = make(map[string]struct{}, len(config.symbols))
+		for _, symbol := range normalizeSymbols(config.symbols) {
+			c.symbols[symbol] = struct{}{}
+		}
+	}
+	if c.backpressure == BackpressureConflate {
+		c.positions = make(map[string]int)
+	}
+
+	b.lock.Lock()
+	b.consumers[c] = struct{}{}
+	b.lock.Unlock()
+	go c.pump()
+	return c
+}
+
+// Publish delivers the quote to the consumers of its symbol. It only waits for the consumers with the block policy,
+// until they make room, are closed or the context is done.
+func (b *QuoteBroker) Publish(ctx context.Context, quote model.WebsocketQuote) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	symbol := strings.ToLower(quote.Symbol)
+	for c := range b.consumers {
+		if c.accepts(symbol) {
+			c.push(ctx, symbol, quote)
+		}
+	}
+}
+
+// Dropped returns the number of quotes dropped by all consumers, including the ones that have been closed.
+func (b *QuoteBroker) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+func (b *QuoteBroker) remove(c *QuoteConsumer) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.consumers, c)
+}
+
+func (b *QuoteBroker) drop(c *QuoteConsumer, quote model.WebsocketQuote) {
+	c.dropped.Add(1)
+	b.dropped.Add(1)
+	if b.onDrop != nil {
+		b.onDrop(quote)
+	}
+}
+
+// QuoteConsumer receives quotes from a QuoteBroker.
+type QuoteConsumer struct {
+	broker       *QuoteBroker
+	backpressure Backpressure
+	size         int
+	symbols      map[string]struct{}
+
+	lock  sync.Mutex
+	queue []model.WebsocketQuote
+	// head is the number of quotes removed from the front of the queue, so that positions stay valid.
+	head int
+	// positions holds the position of the queued quote of each symbol when conflating.
+	positions map[string]int
+
+	out    chan model.WebsocketQuote
+	notify chan struct{}
+	space  chan struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+
+	dropped atomic.Uint64
+}
+
+// Quotes returns the channel of the quotes of the consumer. It is closed once the consumer is closed.
+func (c *QuoteConsumer) Quotes() <-chan model.WebsocketQuote {
+	return c.out
+}
+
+// Dropped returns the number of quotes the consumer dropped or conflated.
+func (c *QuoteConsumer) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Close removes the consumer from the broker and closes its channel. Buffered quotes are discarded.
+func (c *QuoteConsumer) Close() {
+	c.closeOnce.Do(func() {
+		// The consumer is closed first, so that a publish blocked on it returns and the broker can be locked.
+		close(c.done)
+		c.broker.remove(c)
+	})
+}
+
+func (c *QuoteConsumer) accepts(symbol string) bool {
+	if c.symbols == nil {
+		return true
+	}
+	_, ok := c.symbols[symbol]
+	return ok
+}
+
+// push buffers the quote according to the backpressure policy of the consumer.
+func (c *QuoteConsumer) push(ctx context.Context, symbol string, quote model.WebsocketQuote) {
+	for {
+		c.lock.Lock()
+		if c.backpressure == BackpressureConflate {
+			if position, ok := c.positions[symbol]; ok {
+				dropped := c.queue[position-c.head]
+				c.queue[position-c.head] = quote
+				c.lock.Unlock()
+				c.broker.drop(c, dropped)
+				return
+			}
+		}
+		if len(c.queue) < c.size {
+			c.enqueue(symbol, quote)
+			c.lock.Unlock()
+			signal(c.notify)
+			return
+		}
+		if c.backpressure != BackpressureBlock {
+			dropped := c.dequeue()
+			c.enqueue(symbol, quote)
+			c.lock.Unlock()
+			c.broker.drop(c, dropped)
+			return
+		}
+		c.lock.Unlock()
+
+		select {
+		case <-c.space:
+		case <-c.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *QuoteConsumer) enqueue(symbol string, quote model.WebsocketQuote) {
+	if c.positions != nil {
+		c.positions[symbol] = c.head + len(c.queue)
+	}
+	c.queue = append(c.queue, quote)
+}
+
+func (c *QuoteConsumer) dequeue() model.WebsocketQuote {
+	quote := c.queue[0]
+	c.queue = c.queue[1:]
+	if c.positions != nil {
+		delete(c.positions, strings.ToLower(quote.Symbol))
+	}
+	c.head++
+	return quote
+}
+
+// pump sends the buffered quotes to the channel of the consumer until it is closed.
+func (c *QuoteConsumer) pump() {
+	defer close(c.out)
+	for {
+		c.lock.Lock()
+		if len(c.queue) == 0 {
+			c.lock.Unlock()
+			select {
+			case <-c.notify:
+				continue
+			case <-c.done:
+				return
+			}
+		}
+		quote := c.dequeue()
+		c.lock.Unlock()
+		signal(c.space)
+
+		select {
+		case c.out <- quote:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// signal wakes up the goroutine waiting on the channel, if any, without blocking.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
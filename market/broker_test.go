@@ -0,0 +1,156 @@
+package market
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func testQuote(symbol string, lastPrice int64) model.WebsocketQuote {
+	return model.WebsocketQuote{Symbol: symbol, LastPrice: decimal.NewFromInt(lastPrice), LastUpdated: time.Now().UnixMilli()}
+}
+
+// receiveQuotes receives n quotes of the consumer and returns their symbol and last price.
+func receiveQuotes(t *testing.T, c *QuoteConsumer, n int) []string {
+	t.Helper()
+	var got []string
+	for range n {
+		select {
+		case q := <-c.Quotes():
+			got = append(got, q.Symbol+"@"+q.LastPrice.String())
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d quotes", len(got), n)
+		}
+	}
+	return got
+}
+
+// awaitBuffered waits until the pump of the consumer holds a quote and the rest is buffered.
+func awaitBuffered(t *testing.T, c *QuoteConsumer, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return len(c.queue) == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestQuoteBroker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success:symbol filter", func(t *testing.T) {
+		broker := NewQuoteBroker()
+		all := broker.Consume()
+		defer all.Close()
+		aapl := broker.Consume(WithConsumerSymbols("AAPL"))
+		defer aapl.Close()
+
+		broker.Publish(ctx, testQuote("msft", 1))
+		broker.Publish(ctx, testQuote("aapl", 2))
+		assert.Equal(t, []string{"msft@1", "aapl@2"}, receiveQuotes(t, all, 2))
+		assert.Equal(t, []string{"aapl@2"}, receiveQuotes(t, aapl, 1))
+	})
+	t.Run("success:drop oldest", func(t *testing.T) {
+		broker := NewQuoteBroker()
+		c := broker.Consume(WithConsumerBuffer(2), WithBackpressure(BackpressureDropOldest))
+		defer c.Close()
+
+		broker.Publish(ctx, testQuote("aapl", 1))
+		awaitBuffered(t, c, 0)
+		for price := range int64(4) {
+			broker.Publish(ctx, testQuote("aapl", price+2))
+		}
+		assert.Equal(t, []string{"aapl@1", "aapl@4", "aapl@5"}, receiveQuotes(t, c, 3))
+		assert.Equal(t, uint64(2), c.Dropped())
+		assert.Equal(t, uint64(2), broker.Dropped())
+	})
+	t.Run("success:conflate", func(t *testing.T) {
+		broker := NewQuoteBroker()
+		c := broker.Consume(WithConsumerBuffer(2), WithBackpressure(BackpressureConflate))
+		defer c.Close()
+
+		broker.Publish(ctx, testQuote("aapl", 1))
+		awaitBuffered(t, c, 0)
+		broker.Publish(ctx, testQuote("aapl", 2))
+		broker.Publish(ctx, testQuote("msft", 3))
+		broker.Publish(ctx, testQuote("AAPL", 4))
+		broker.Publish(ctx, testQuote("msft", 5))
+		assert.Equal(t, []string{"aapl@1", "AAPL@4", "msft@5"}, receiveQuotes(t, c, 3))
+		assert.Equal(t, uint64(2), c.Dropped())
+
+		broker.Publish(ctx, testQuote("aapl", 6))
+		awaitBuffered(t, c, 0)
+		broker.Publish(ctx, testQuote("msft", 7))
+		broker.Publish(ctx, testQuote("nvda", 8))
+		broker.Publish(ctx, testQuote("amzn", 9))
+		assert.Equal(t, []string{"aapl@6", "nvda@8", "amzn@9"}, receiveQuotes(t, c, 3), "the oldest symbol is dropped once full")
+		assert.Equal(t, uint64(3), c.Dropped())
+	})
+	t.Run("success:block", func(t *testing.T) {
+		broker := NewQuoteBroker()
+		c := broker.Consume(WithConsumerBuffer(1))
+		defer c.Close()
+
+		broker.Publish(ctx, testQuote("aapl", 1))
+		awaitBuffered(t, c, 0)
+		broker.Publish(ctx, testQuote("aapl", 2))
+		published := make(chan struct{})
+		go func() {
+			broker.Publish(ctx, testQuote("aapl", 3))
+			close(published)
+		}()
+		select {
+		case <-published:
+			t.Fatal("publish should block while the buffer is full")
+		case <-time.After(20 * time.Millisecond):
+		}
+		assert.Equal(t, []string{"aapl@1", "aapl@2", "aapl@3"}, receiveQuotes(t, c, 3))
+		<-published
+		assert.Zero(t, c.Dropped())
+	})
+	t.Run("success:close unblocks publish", func(t *testing.T) {
+		broker := NewQuoteBroker()
+		c := broker.Consume(WithConsumerBuffer(1))
+		broker.Publish(ctx, testQuote("aapl", 1))
+		awaitBuffered(t, c, 0)
+		broker.Publish(ctx, testQuote("aapl", 2))
+		time.AfterFunc(10*time.Millisecond, c.Close)
+		broker.Publish(ctx, testQuote("aapl", 3))
+
+		require.Eventually(t, func() bool {
+			_, ok := <-c.Quotes()
+			return !ok
+		}, time.Second, time.Millisecond, "the channel should be closed")
+		broker.Publish(ctx, testQuote("aapl", 4))
+	})
+}
+
+func TestWebsocketClient_Consume(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+	client := newWebsocketTestClient(t, server)
+	require.NoError(t, client.Connect(server.WebsocketURL()))
+	defer func() { assert.NoError(t, client.Disconnect()) }()
+	require.NoError(t, client.Subscribe(context.Background(), []string{"AAPL", "MSFT"}))
+
+	// The slow consumer never receives, which must not hold up the other one nor the acknowledgments.
+	slow := client.Consume(WithConsumerBuffer(1), WithBackpressure(BackpressureDropOldest))
+	defer slow.Close()
+	msft := client.Consume(WithConsumerSymbols("MSFT"))
+	defer msft.Close()
+
+	for price := range int64(5) {
+		server.PublishQuote(testQuote("aapl", price))
+		server.PublishQuote(testQuote("msft", price))
+	}
+	assert.Equal(t, []string{"msft@0", "msft@1", "msft@2", "msft@3", "msft@4"}, receiveQuotes(t, msft, 5))
+	require.NoError(t, client.Unsubscribe(context.Background(), []string{"AAPL"}))
+	assert.Positive(t, client.DroppedQuotes())
+}
@@ -0,0 +1,289 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"sync"
+
+	"go.tradeforge.dev/fmp/encoder"
+	"go.tradeforge.dev/fmp/model"
+)
+
+const (
+	// DefaultBulkConcurrency is the number of bulk parts fetched at the same time unless configured otherwise.
+	DefaultBulkConcurrency = 2
+
+	// bulkRowBuffer is the number of rows of a part that are decoded ahead of the consumer.
+	bulkRowBuffer = 64
+)
+
+// ErrTooManySkippedRows is returned by lenient bulk calls that skipped more rows than their policy allows.
+var ErrTooManySkippedRows = errors.New("too many skipped rows")
+
+type bulkConfig struct {
+	concurrency int
+	requestOpts []model.RequestOption
+}
+
+// BulkOption changes how the parts of a bulk endpoint are fetched.
+type BulkOption func(b *bulkConfig)
+
+// WithBulkConcurrency sets the number of parts fetched at the same time. It defaults to DefaultBulkConcurrency.
+func WithBulkConcurrency(n int) BulkOption {
+	return func(b *bulkConfig) {
+		b.concurrency = n
+	}
+}
+
+// WithBulkRequestOptions applies the request options to the request of every part.
+func WithBulkRequestOptions(opts ...model.RequestOption) BulkOption {
+	return func(b *bulkConfig) {
+		b.requestOpts = append(b.requestOpts, opts...)
+	}
+}
+
+// bulkRow is a decoded row of a part, or the error that ended the part.
+type bulkRow[T any] struct {
+	value T
+	err   error
+}
+
+// bulkPart streams the rows of a part. The rows channel is closed once the part is exhausted, and done once its
+// stream function returned.
+type bulkPart[T any] struct {
+	rows chan bulkRow[T]
+	last bool
+	done chan struct{}
+}
+
+// AllCompanyProfiles returns an iterator over the company profiles of all parts of the bulk endpoint, in part order.
+//
+// Parts are streamed as they arrive and decoded row by row, so memory does not grow with the size of a part.
+// Up to the configured number of parts are fetched ahead of the consumer. Iteration stops after the last part,
+// which FMP signals with 400 Bad Request, or after the first error, which is yielded.
+func (tc *TickerClient) AllCompanyProfiles(ctx context.Context, opts ...BulkOption) iter.Seq2[model.BulkCompanyProfileResponse, error] {
+	config := bulkConfig{concurrency: DefaultBulkConcurrency}
+	for _, o := range opts {
+		o(&config)
+	}
+	return func(yield func(model.BulkCompanyProfileResponse, error) bool) {
+		streamParts(ctx, config, func(ctx context.Context, part int, rows chan<- bulkRow[model.BulkCompanyProfileResponse]) bool {
+			return tc.streamCompanyProfiles(ctx, part, config.requestOpts, rows)
+		}, yield)
+	}
+}
+
+// streamParts fetches parts with stream from part 0 on and yields their rows in part order. The stream function
+// sends the rows of a part and reports whether the part was past the last one.
+//
+// No more than the configured number of parts are fetched past the first part that is not fetched yet, and no part
+// is requested once a part past the last one is seen, so that requests past the end do not use up the quota.
+func streamParts[T any](
+	ctx context.Context,
+	config bulkConfig,
+	stream func(ctx context.Context, part int, rows chan<- bulkRow[T]) bool,
+	yield func(T, error) bool,
+) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	concurrency := max(config.concurrency, 1)
+	var endLock sync.Mutex
+	end := -1
+	ended := func(part int) bool {
+		endLock.Lock()
+		defer endLock.Unlock()
+		return end >= 0 && part > end
+	}
+
+	// The queue holds the parts being fetched ahead of the consumer and bounds their number.
+	queue := make(chan *bulkPart[T], concurrency-1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// The window holds the parts being fetched, from the first one that is not fetched yet.
+		var window []*bulkPart[T]
+		for part := 0; ; part++ {
+			for len(window) > 0 {
+				select {
+				case <-window[0].done:
+					window = window[1:]
+					continue
+				default:
+				}
+				if len(window) < concurrency {
+					break
+				}
+				select {
+				case <-window[0].done:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ended(part) {
+				return
+			}
+
+			p := &bulkPart[T]{rows: make(chan bulkRow[T], bulkRowBuffer), done: make(chan struct{})}
+			select {
+			case queue <- p:
+			case <-ctx.Done():
+				return
+			}
+			window = append(window, p)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(p.done)
+				p.last = stream(ctx, part, p.rows)
+				if p.last {
+					endLock.Lock()
+					if end < 0 || part < end {
+						end = part
+					}
+					endLock.Unlock()
+				}
+				close(p.rows)
+			}()
+		}
+	}()
+
+	for {
+		var p *bulkPart[T]
+		select {
+		case p = <-queue:
+		case <-ctx.Done():
+			var zero T
+			yield(zero, ctx.Err())
+			return
+		}
+		for row := range p.rows {
+			if !yield(row.value, row.err) || row.err != nil {
+				return
+			}
+		}
+		// The rows channel is closed once the stream function returned, so reading last does not race.
+		if p.last {
+			return
+		}
+		if err := ctx.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+	}
+}
+
+// streamCompanyProfiles sends the company profiles of the part to rows and reports whether the part was past the
+// last one.
+func (tc *TickerClient) streamCompanyProfiles(
+	ctx context.Context,
+	part int,
+	opts []model.RequestOption,
+	rows chan<- bulkRow[model.BulkCompanyProfileResponse],
+) bool {
+	send := func(row bulkRow[model.BulkCompanyProfileResponse]) bool {
+		select {
+		case rows <- row:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	res, body, err := tc.Open(
+		ctx,
+		http.MethodGet,
+		BulkGetCompanyProfilePath,
+		&model.BulkGetCompanyProfilesParams{Part: part},
+		// FMP sends a 400 status code when there is no more data to fetch.
+		append(opts, model.WithContentType("text/csv"), model.WithIgnoredErrorStatusCodes(http.StatusBadRequest))...,
+	)
+	if err != nil {
+		send(bulkRow[model.BulkCompanyProfileResponse]{err: fmt.Errorf("fetching part %d: %w", part, err)})
+		return false
+	}
+	defer body.Close()
+	if res.StatusCode() == http.StatusBadRequest {
+		return true
+	}
+
+	for profile, err := range readCompanyProfiles(body) {
+		if err != nil {
+			err = fmt.Errorf("reading part %d: %w", part, err)
+		}
+		if !send(bulkRow[model.BulkCompanyProfileResponse]{value: profile, err: err}) || err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// readCompanyProfiles returns an iterator over the company profiles of a CSV body. Iteration stops after the first
+// error, which is yielded.
+func readCompanyProfiles(r io.Reader) iter.Seq2[model.BulkCompanyProfileResponse, error] {
+	return func(yield func(model.BulkCompanyProfileResponse, error) bool) {
+		decoder, err := encoder.NewCSVDecoder[model.BulkCompanyProfileResponse](r)
+		if err != nil {
+			yield(model.BulkCompanyProfileResponse{}, err)
+			return
+		}
+		for {
+			var p model.BulkCompanyProfileResponse
+			err := decoder.Decode(&p)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(model.BulkCompanyProfileResponse{}, fmt.Errorf("parsing record: %w", err))
+				return
+			}
+			if !yield(p, nil) {
+				return
+			}
+		}
+	}
+}
+
+// decodeLenient decodes the rows of a CSV body, skipping the ones that cannot be decoded. It fails with
+// ErrTooManySkippedRows, together with the partial result, once the policy is exceeded.
+func decodeLenient[T any](r io.Reader, policy model.SkipPolicy) (*model.PartialResult[T], error) {
+	decoder, err := encoder.NewCSVDecoder[T](r)
+	if err != nil {
+		return nil, err
+	}
+	res := &model.PartialResult[T]{}
+	for {
+		var v T
+		err := decoder.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *encoder.RowError
+		if errors.As(err, &rowErr) {
+			res.Skipped = append(res.Skipped, model.SkippedRow{Row: rowErr.Row, Line: rowErr.Line, Record: rowErr.Record, Err: rowErr.Err})
+			if policy.MaxSkippedRows > 0 && len(res.Skipped) > policy.MaxSkippedRows {
+				return res, fmt.Errorf("%w: more than %d rows: %w", ErrTooManySkippedRows, policy.MaxSkippedRows, rowErr)
+			}
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("parsing record: %w", err)
+		}
+		res.Rows = append(res.Rows, v)
+	}
+
+	total := len(res.Rows) + len(res.Skipped)
+	if ratio := float64(len(res.Skipped)) / float64(max(total, 1)); policy.MaxSkippedRatio > 0 && ratio > policy.MaxSkippedRatio {
+		return res, fmt.Errorf("%w: %d of %d rows", ErrTooManySkippedRows, len(res.Skipped), total)
+	}
+	return res, nil
+}
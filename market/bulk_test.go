@@ -0,0 +1,156 @@
+package market
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func TestAllCompanyProfiles(t *testing.T) {
+	// The fixtures of the bulk profiles have 8 rows in part 0 and 5 rows in part 1.
+	tests := []struct {
+		name    string
+		opts    []BulkOption
+		take    int
+		wantLen int
+	}{
+		{name: "success:sequential parts", opts: []BulkOption{WithBulkConcurrency(1)}, wantLen: 13},
+		{name: "success:concurrent parts", opts: []BulkOption{WithBulkConcurrency(4)}, wantLen: 13},
+		{name: "success:early break", take: 3, wantLen: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fmptest.NewServer()
+			defer server.Close()
+			client := newFixtureTestClient(server)
+
+			var symbols []string
+			for p, err := range client.AllCompanyProfiles(context.Background(), tt.opts...) {
+				require.NoError(t, err)
+				symbols = append(symbols, p.Symbol)
+				if len(symbols) == tt.take {
+					break
+				}
+			}
+			require.Len(t, symbols, tt.wantLen)
+			assert.Equal(t, "AAPL", symbols[0], "parts should be yielded in order")
+			if tt.wantLen == 13 {
+				assert.Equal(t, "V", symbols[8], "parts should be yielded in order")
+			}
+		})
+	}
+
+	t.Run("error:failed part", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newFixtureTestClient(server)
+		server.Fail(BulkGetCompanyProfilePath, fmptest.Fault{StatusCode: http.StatusPaymentRequired, Message: fmptest.MessageRestrictedEndpoint})
+
+		var errs []error
+		for _, err := range client.AllCompanyProfiles(context.Background()) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		assert.ErrorContains(t, errs[0], "fetching part 0")
+	})
+	t.Run("error:cancelled context", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newFixtureTestClient(server)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var errs []error
+		for _, err := range client.AllCompanyProfiles(ctx) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], context.Canceled)
+	})
+}
+
+func TestStreamParts(t *testing.T) {
+	// Parts 0 to 2 have a row each and are slow to fetch; part 3 is past the last one and answers right away.
+	var lock sync.Mutex
+	var requested []int
+	stream := func(ctx context.Context, part int, rows chan<- bulkRow[int]) bool {
+		lock.Lock()
+		requested = append(requested, part)
+		lock.Unlock()
+		if part >= 3 {
+			return true
+		}
+		time.Sleep(time.Duration(3-part) * 20 * time.Millisecond)
+		rows <- bulkRow[int]{value: part}
+		return false
+	}
+
+	var got []int
+	streamParts(context.Background(), bulkConfig{concurrency: 2}, stream, func(v int, err error) bool {
+		require.NoError(t, err)
+		got = append(got, v)
+		return true
+	})
+	assert.Equal(t, []int{0, 1, 2}, got)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, requested, "no part should be requested past the end")
+}
+
+func TestBulkGetCompanyProfileLenient(t *testing.T) {
+	fixtures := fstest.MapFS{
+		"stable/profile-bulk/0.csv": {Data: []byte("symbol,price,ipoDate,isEtf\n" +
+			"AAPL,243.85,1980-12-12,false\n" +
+			"MSFT,418.58\n" +
+			"NVDA,138.31,1999-01-22,false\n" +
+			"SPY,591.64,1993-01-22,maybe\n" +
+			"AMZN,220.22,1997-05-15,false\n")},
+	}
+	server := fmptest.NewServer(fmptest.WithFixtures(fixtures))
+	defer server.Close()
+	client := newFixtureTestClient(server)
+	ctx := context.Background()
+	params := &model.BulkGetCompanyProfilesParams{Part: 0}
+
+	t.Run("success:skipped rows", func(t *testing.T) {
+		res, err := client.BulkGetCompanyProfileLenient(ctx, params, model.SkipPolicy{})
+		require.NoError(t, err)
+		require.Len(t, res.Rows, 3)
+		assert.Equal(t, "AMZN", res.Rows[2].Symbol)
+		require.Len(t, res.Skipped, 2)
+		assert.Equal(t, 2, res.Skipped[0].Row)
+		assert.Equal(t, 3, res.Skipped[0].Line)
+		assert.Equal(t, []string{"MSFT", "418.58"}, res.Skipped[0].Record)
+		assert.ErrorContains(t, res.Skipped[0].Err, "invalid record length")
+		assert.Equal(t, 4, res.Skipped[1].Row)
+		assert.ErrorContains(t, res.Skipped[1].Err, `decoding column "isEtf"`)
+	})
+	t.Run("success:no more data", func(t *testing.T) {
+		res, err := client.BulkGetCompanyProfileLenient(ctx, &model.BulkGetCompanyProfilesParams{Part: 1}, model.SkipPolicy{})
+		require.NoError(t, err)
+		assert.Empty(t, res.Rows)
+	})
+	t.Run("error:too many skipped rows", func(t *testing.T) {
+		res, err := client.BulkGetCompanyProfileLenient(ctx, params, model.SkipPolicy{MaxSkippedRows: 1})
+		require.ErrorIs(t, err, ErrTooManySkippedRows)
+		assert.Len(t, res.Skipped, 2)
+	})
+	t.Run("error:too high skipped ratio", func(t *testing.T) {
+		_, err := client.BulkGetCompanyProfileLenient(ctx, params, model.SkipPolicy{MaxSkippedRatio: 0.25})
+		require.ErrorIs(t, err, ErrTooManySkippedRows)
+		assert.ErrorContains(t, err, "2 of 5 rows")
+	})
+	t.Run("error:strict decoding", func(t *testing.T) {
+		_, err := client.BulkGetCompanyProfile(ctx, params)
+		assert.ErrorContains(t, err, "row 2 (line 3): invalid record length")
+	})
+}
@@ -0,0 +1,25 @@
+package market
+
+import "time"
+
+// DefaultCacheTTLs returns how long responses are cached per endpoint. Endpoints that are not listed are never cached.
+func DefaultCacheTTLs() map[string]time.Duration {
+	return map[string]time.Duration{
+		GetQuotePath:       time.Second,
+		BatchGetQuotesPath: time.Second,
+
+		GetCompanyProfilePath:         24 * time.Hour,
+		GetFinancialKeyMetricsTTMPath: 24 * time.Hour,
+		GetFinancialRatiosTTMPath:     24 * time.Hour,
+		GetAvailableExchangesPath:     24 * time.Hour,
+
+		GetSP500IndexConstituentsPath:    24 * time.Hour,
+		GetNasdaqIndexConstituentsPath:   24 * time.Hour,
+		GetDowJonesIndexConstituentsPath: 24 * time.Hour,
+
+		GetIndexListPath:          24 * time.Hour,
+		GetHistoricalSP500Path:    24 * time.Hour,
+		GetHistoricalNasdaqPath:   24 * time.Hour,
+		GetHistoricalDowJonesPath: 24 * time.Hour,
+	}
+}
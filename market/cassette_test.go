@@ -0,0 +1,64 @@
+package market
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/client/rest"
+	"go.tradeforge.dev/fmp/client/vcr"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func TestCassettes(t *testing.T) {
+	recorded, err := filepath.Glob(filepath.Join("testdata", "cassettes", "*.json"))
+	require.NoError(t, err)
+	synthetic, err := filepath.Glob(filepath.Join("testdata", "cassettes", "synthetic", "*.json"))
+	require.NoError(t, err)
+	paths := append(recorded, synthetic...)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			cassette, err := vcr.Load(path)
+			require.NoError(t, err)
+			require.NotEmpty(t, cassette.Interactions)
+			for _, interaction := range cassette.Interactions {
+				u, err := url.Parse(interaction.Request.URL)
+				require.NoError(t, err)
+				assert.False(t, u.Query().Has("apikey"), "the API key must be scrubbed from %s", interaction.Request.URL)
+				assert.Nil(t, u.User)
+				for key := range interaction.Response.Headers {
+					assert.Contains(t, vcr.RecordedHeaders, key, "the header %s must not be recorded", key)
+				}
+			}
+		})
+	}
+}
+
+func TestGetQuote_Replay(t *testing.T) {
+	// The cassette replays the fixture of the local stand-in server rather than a response of the API.
+	recorder, err := vcr.New(filepath.Join("testdata", "cassettes", "synthetic", "GetQuote.json"), vcr.ModeReplay)
+	require.NoError(t, err)
+	client := NewHTTPClient(
+		HTTPClientConfig{APIKey: "replay-api-key"},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		rest.WithTransport(recorder),
+		rest.WithRetryPolicy(rest.RetryPolicy{}),
+	)
+
+	res, err := client.GetQuote(context.Background(), &model.GetQuoteParams{Symbol: "AAPL"})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.Equal(t, "AAPL", res.Symbol)
+	assert.Equal(t, "243.85", res.Price.String())
+
+	_, err = client.GetQuote(context.Background(), &model.GetQuoteParams{Symbol: "MSFT"})
+	require.ErrorIs(t, err, vcr.ErrInteractionNotFound, "requests missing from the cassette are not sent to the API")
+}
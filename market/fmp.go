@@ -6,8 +6,8 @@ import (
 	"errors"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 
-	"github.com/gorilla/websocket"
 	"go.tradeforge.dev/background/manager"
 
 	"go.tradeforge.dev/fmp/client/rest"
@@ -16,6 +16,15 @@ import (
 
 type HTTPClientConfig struct {
 	APIKey string `json:"-" validate:"required" env:"FMP_API_KEY"` //nolint:gosec // not a hardcoded credential
+
+	// BaseURL overrides the URL of the FMP API, e.g. to point the client at a local stand-in server.
+	BaseURL string `json:"baseURL" validate:"omitempty,url" env:"FMP_API_URL"`
+
+	// Plan enables client-side rate limiting according to the budget of the FMP plan tier. Calls are not throttled if empty.
+	Plan rest.Plan `json:"plan" validate:"omitempty,oneof=basic starter premium ultimate" env:"FMP_PLAN"`
+
+	// CacheSize enables the in-memory response cache with the given number of entries. Responses are not cached if zero.
+	CacheSize int `json:"cacheSize" validate:"gte=0" env:"FMP_CACHE_SIZE"`
 }
 
 // HTTPClient defines a client to the Polygon REST API.
@@ -31,13 +40,30 @@ type HTTPClient struct {
 }
 
 // NewHTTPClient returns a new HTTP client with the specified API key and config.
+// Options are passed on to the underlying REST client.
 func NewHTTPClient(
 	config HTTPClientConfig,
 	logger *slog.Logger,
+	opts ...rest.Option,
 ) *HTTPClient {
+	if config.Plan != "" {
+		limiter, err := rest.NewPlanLimiter(config.Plan)
+		if err != nil {
+			logger.Warn("rate limiting disabled", slog.Any("error", err))
+		} else {
+			opts = append([]rest.Option{rest.WithRateLimiter(limiter)}, opts...)
+		}
+	}
+	if config.BaseURL != "" {
+		opts = append([]rest.Option{rest.WithBaseURL(config.BaseURL)}, opts...)
+	}
+	if config.CacheSize > 0 {
+		opts = append([]rest.Option{rest.WithCache(rest.NewLRUCache(config.CacheSize), DefaultCacheTTLs())}, opts...)
+	}
 	c := rest.New(
 		config.APIKey,
 		logger,
+		opts...,
 	)
 
 	return &HTTPClient{
@@ -79,31 +105,68 @@ type WebsocketClient struct {
 
 	manager *manager.Manager
 
-	connectOnce    sync.Once
+	reconnectPolicy ReconnectPolicy
+	onStateChange   func(change ConnectionStateChange)
+
+	stateLock sync.Mutex
+	state     ConnectionState
+
 	connectionLock sync.Mutex
-	connection     *websocket.Conn
+	connection     *websocketConnection
+	session        *websocketSession
+
+	subscribeQuotesLock   sync.RWMutex
+	subscriptions         *subscriptionRegistry
+	subscriptionBatchSize int
 
-	subscribeQuotesLock sync.RWMutex
+	broker         *QuoteBroker
+	quotesOnce     sync.Once
+	quotesConsumer *QuoteConsumer
 
-	events chan model.WebsocketMesssage
-	quotes chan model.WebsocketQuote
+	watchdog *watchdog
+
+	book     *QuoteBook
+	seedFrom *QuoteClient
+	seedOpts []model.RequestOption
+
+	feed       model.WebsocketFeed
+	eventsOnce sync.Once
+	events     atomic.Pointer[chan model.WebsocketEvent]
+
+	recorder *recorder
+
+	failover *PollingQuoteSource
+
+	metrics *websocketMetrics
 }
 
 func NewWebsocketClient(
 	ctx context.Context,
 	config WebsocketClientConfig,
 	logger *slog.Logger,
+	opts ...WebsocketOption,
 ) (*WebsocketClient, error) {
 	if ctx.Done() != nil {
 		return nil, errors.New("context is already cancelled")
 	}
-	return &WebsocketClient{
+	wss := &WebsocketClient{
 		ctx:     ctx,
 		config:  config,
 		logger:  logger,
 		manager: manager.New(ctx, manager.WithCancelOnError(), manager.WithFirstError()),
 
-		events: make(chan model.WebsocketMesssage),
-		quotes: make(chan model.WebsocketQuote),
-	}, nil
+		reconnectPolicy:       DefaultReconnectPolicy(),
+		state:                 ConnectionStateClosed,
+		subscriptions:         newSubscriptionRegistry(),
+		subscriptionBatchSize: DefaultSubscriptionBatchSize,
+
+		broker:   NewQuoteBroker(),
+		watchdog: newWatchdog(),
+		book:     NewQuoteBook(),
+	}
+	for _, o := range opts {
+		o(wss)
+	}
+	wss.broker.onDrop = wss.metrics.recordDroppedQuote
+	return wss, nil
 }
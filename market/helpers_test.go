@@ -1,21 +1,67 @@
 package market
 
 import (
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/client/rest"
+	"go.tradeforge.dev/fmp/client/vcr"
+	"go.tradeforge.dev/fmp/fmptest"
 )
 
+// newTestHTTPClient returns a client to the FMP API if FMP_API_KEY is set and to a local stand-in server otherwise.
+//
+// Setting FMP_VCR_MODE to record, replay or replay-or-record records and replays the responses of the API in
+// testdata/cassettes. Recorded cassettes are replayed without FMP_API_KEY.
 func newTestHTTPClient(t *testing.T) *HTTPClient {
 	t.Helper()
 
-	apiKey := os.Getenv("FMP_API_KEY")
-	if apiKey == "" {
-		t.Skip("FMP_API_KEY not set, skipping integration test")
+	config := HTTPClientConfig{
+		APIKey:  os.Getenv("FMP_API_KEY"),
+		BaseURL: os.Getenv("FMP_API_URL"),
+	}
+	var opts []rest.Option
+
+	cassette := filepath.Join("testdata", "cassettes", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+	mode := vcr.Mode(os.Getenv("FMP_VCR_MODE"))
+	if mode == "" && config.APIKey == "" {
+		if _, err := os.Stat(cassette); err == nil {
+			mode = vcr.ModeReplay
+		}
+	}
+
+	switch {
+	case mode != "":
+		recorder, err := vcr.New(cassette, mode)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, recorder.Save())
+		})
+		opts = append(opts, rest.WithTransport(recorder))
+	case config.APIKey == "":
+		server := fmptest.NewServer()
+		t.Cleanup(server.Close)
+		config.APIKey = server.APIKey()
+		config.BaseURL = server.URL
 	}
 
 	return NewHTTPClient(
-		HTTPClientConfig{APIKey: apiKey},
+		config,
 		slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		opts...,
+	)
+}
+
+// newFixtureTestClient returns a client to the local stand-in server, for tests that rely on its fixtures or faults.
+func newFixtureTestClient(server *fmptest.Server) *HTTPClient {
+	return NewHTTPClient(
+		HTTPClientConfig{APIKey: server.APIKey(), BaseURL: server.URL},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
 	)
 }
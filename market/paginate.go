@@ -0,0 +1,92 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+// DefaultPageSize is the number of items requested per page unless configured otherwise.
+const DefaultPageSize = 100
+
+// PageFunc fetches a page of results, e.g. a method value such as client.GetStockNews.
+type PageFunc[P any, T any] func(ctx context.Context, params P, opts ...model.RequestOption) ([]T, error)
+
+type pagination struct {
+	pageSize    uint
+	maxPages    uint
+	requestOpts []model.RequestOption
+}
+
+// PageOption changes how Paginate walks the pages.
+type PageOption func(p *pagination)
+
+// WithPageSize sets the number of items requested per page. It defaults to DefaultPageSize.
+func WithPageSize(size uint) PageOption {
+	return func(p *pagination) {
+		p.pageSize = size
+	}
+}
+
+// WithMaxPages limits the number of pages that are fetched. All pages are fetched if zero.
+func WithMaxPages(n uint) PageOption {
+	return func(p *pagination) {
+		p.maxPages = n
+	}
+}
+
+// WithPageRequestOptions applies the request options to every page request.
+func WithPageRequestOptions(opts ...model.RequestOption) PageOption {
+	return func(p *pagination) {
+		p.requestOpts = append(p.requestOpts, opts...)
+	}
+}
+
+// Paginate returns an iterator over the items of all pages returned by fetch, starting with page 0.
+//
+// Pages are fetched lazily while the items are consumed, so breaking out of the loop stops further requests.
+// Iteration stops after an empty page, a page shorter than the page size or the maximum number of pages.
+// Errors of fetch and of the context are yielded once and end the iteration. Every page is a separate call
+// and is therefore subject to the rate limiter of the client.
+//
+//	for article, err := range market.Paginate(ctx, client.GetStockNews, model.GetNewsParams{Symbols: "AAPL"}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func Paginate[P model.Pageable[P], T any](ctx context.Context, fetch PageFunc[P, T], params P, opts ...PageOption) iter.Seq2[T, error] {
+	p := pagination{pageSize: DefaultPageSize}
+	for _, o := range opts {
+		o(&p)
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		if p.pageSize == 0 {
+			yield(zero, fmt.Errorf("invalid page size: %d", p.pageSize))
+			return
+		}
+		for page := uint(0); p.maxPages == 0 || page < p.maxPages; page++ {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			items, err := fetch(ctx, params.WithPage(page, p.pageSize), p.requestOpts...)
+			if err != nil {
+				yield(zero, fmt.Errorf("fetching page %d: %w", page, err))
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if uint(len(items)) < p.pageSize {
+				return
+			}
+		}
+	}
+}
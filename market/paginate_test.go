@@ -0,0 +1,81 @@
+package market
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func TestPaginate(t *testing.T) {
+	// The fixture of the house disclosures has 40 rows.
+	tests := []struct {
+		name     string
+		opts     []PageOption
+		take     int
+		wantLen  int
+		wantHits int
+	}{
+		{name: "success:short last page", opts: []PageOption{WithPageSize(15)}, wantLen: 40, wantHits: 3},
+		{name: "success:empty last page", opts: []PageOption{WithPageSize(20)}, wantLen: 40, wantHits: 3},
+		{name: "success:max pages", opts: []PageOption{WithPageSize(15), WithMaxPages(2)}, wantLen: 30, wantHits: 2},
+		{name: "success:early break", opts: []PageOption{WithPageSize(15)}, take: 5, wantLen: 5, wantHits: 1},
+		{name: "success:default page size", wantLen: 40, wantHits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fmptest.NewServer()
+			defer server.Close()
+			client := newFixtureTestClient(server)
+
+			var got []model.FinancialDisclosure
+			for d, err := range Paginate(context.Background(), client.GetHouseFinancialDisclosures, model.GetHouseFinancialDisclosuresParams{}, tt.opts...) {
+				require.NoError(t, err)
+				got = append(got, d)
+				if len(got) == tt.take {
+					break
+				}
+			}
+			assert.Len(t, got, tt.wantLen)
+			assert.Equal(t, tt.wantHits, server.Hits(GetHouseFinancialDisclosuresPath))
+		})
+	}
+
+	t.Run("error:failed page", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newFixtureTestClient(server)
+		server.Fail(GetInsiderTradesPath, fmptest.Fault{StatusCode: http.StatusPaymentRequired, Message: fmptest.MessageRestrictedEndpoint})
+
+		var errs []error
+		for _, err := range Paginate(context.Background(), client.GetInsiderTrades, model.GetInsiderTradesParams{}) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		assert.ErrorContains(t, errs[0], "fetching page 0")
+	})
+	t.Run("error:cancelled context", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newFixtureTestClient(server)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var n int
+		var err error
+		for _, err = range Paginate(ctx, client.GetStockNews, model.GetNewsParams{}, WithPageSize(1)) {
+			if err != nil {
+				break
+			}
+			n++
+			cancel()
+		}
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, n)
+	})
+}
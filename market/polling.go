@@ -0,0 +1,343 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+// DefaultPollingInterval is the time between two polls of a PollingQuoteSource unless configured otherwise.
+const DefaultPollingInterval = 5 * time.Second
+
+// QuoteSource is a stream of quotes of the subscribed symbols. It is implemented by WebsocketClient and
+// PollingQuoteSource, so that consumers do not depend on where the quotes come from.
+type QuoteSource interface {
+	Subscribe(ctx context.Context, symbols []string) error
+	Unsubscribe(ctx context.Context, symbols []string) error
+	Subscriptions() []string
+	Quotes() <-chan model.WebsocketQuote
+	Consume(opts ...ConsumerOption) *QuoteConsumer
+	DroppedQuotes() uint64
+	QuoteBook() *QuoteBook
+}
+
+var (
+	_ QuoteSource = (*WebsocketClient)(nil)
+	_ QuoteSource = (*PollingQuoteSource)(nil)
+)
+
+// PollingOption changes how a PollingQuoteSource polls the quotes.
+type PollingOption func(p *PollingQuoteSource)
+
+// WithPollingInterval sets the time between two polls. It defaults to DefaultPollingInterval.
+func WithPollingInterval(interval time.Duration) PollingOption {
+	return func(p *PollingQuoteSource) {
+		p.interval = interval
+	}
+}
+
+// WithPollingExchange polls all the quotes of the exchange in a single call with BatchGetQuotesByExchange, rather
+// than the quotes of the subscribed symbols with BatchGetQuotes. Only the quotes of the subscribed symbols are
+// emitted. As the quotes of the exchange have no timestamp, they are timestamped with the time of the poll.
+func WithPollingExchange(exchange string) PollingOption {
+	return func(p *PollingQuoteSource) {
+		p.exchange = exchange
+	}
+}
+
+// WithPollingRequestOptions applies the request options to every poll.
+func WithPollingRequestOptions(opts ...model.RequestOption) PollingOption {
+	return func(p *PollingQuoteSource) {
+		p.requestOpts = append(p.requestOpts, opts...)
+	}
+}
+
+// PollingQuoteSource emulates the quote stream of the websocket with the REST endpoints, for when the websocket is
+// unavailable or the plan lacks access to it. It polls the quotes of the subscribed symbols on an interval and
+// emits the ones that changed since the previous poll, in the shape of the websocket quotes.
+//
+// The REST quotes have no bid nor ask. The last size of an emitted quote is the volume traded since the previous
+// poll, so that bars built from it have a meaningful volume; it is zero on the first poll of a symbol.
+type PollingQuoteSource struct {
+	client      *QuoteClient
+	logger      *slog.Logger
+	interval    time.Duration
+	exchange    string
+	requestOpts []model.RequestOption
+
+	subscriptions  *subscriptionRegistry
+	broker         *QuoteBroker
+	book           *QuoteBook
+	quotesOnce     sync.Once
+	quotesConsumer *QuoteConsumer
+
+	lock sync.Mutex
+	// last holds the last polled quote of each symbol, to emit only the ones that changed.
+	last    map[string]polledQuote
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+type polledQuote struct {
+	price     decimal.Decimal
+	volume    decimal.Decimal
+	timestamp int64
+}
+
+// NewPollingQuoteSource returns a source polling the quotes from the client. It does not poll until started.
+func NewPollingQuoteSource(client *QuoteClient, logger *slog.Logger, opts ...PollingOption) *PollingQuoteSource {
+	return newPollingQuoteSource(client, logger, newSubscriptionRegistry(), NewQuoteBroker(), NewQuoteBook(), opts...)
+}
+
+func newPollingQuoteSource(
+	client *QuoteClient,
+	logger *slog.Logger,
+	subscriptions *subscriptionRegistry,
+	broker *QuoteBroker,
+	book *QuoteBook,
+	opts ...PollingOption,
+) *PollingQuoteSource {
+	p := &PollingQuoteSource{
+		client:        client,
+		logger:        logger,
+		interval:      DefaultPollingInterval,
+		subscriptions: subscriptions,
+		broker:        broker,
+		book:          book,
+		last:          make(map[string]polledQuote),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Start polls the quotes on the interval, from now on, until Stop is called or the context is done. Failed polls
+// are logged and retried on the next interval. It does nothing if the source is already started.
+func (p *PollingQuoteSource) Start(ctx context.Context) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.cancel != nil {
+		return
+	}
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.stopped = make(chan struct{})
+	go p.run(ctx, p.stopped)
+}
+
+// Stop stops polling and waits for the poll in progress, if any.
+func (p *PollingQuoteSource) Stop() {
+	p.lock.Lock()
+	cancel, stopped := p.cancel, p.stopped
+	p.cancel, p.stopped = nil, nil
+	p.lock.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-stopped
+}
+
+// Running reports whether the source is started.
+func (p *PollingQuoteSource) Running() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.cancel != nil
+}
+
+func (p *PollingQuoteSource) run(ctx context.Context, stopped chan struct{}) {
+	defer close(stopped)
+	interval := p.interval
+	if interval <= 0 {
+		interval = DefaultPollingInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Poll(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Warn("polling quotes", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the quotes of the subscribed symbols once and emits the ones that changed since the previous poll.
+func (p *PollingQuoteSource) Poll(ctx context.Context) error {
+	symbols := p.subscriptions.symbols()
+	if len(symbols) == 0 {
+		return nil
+	}
+	quotes, err := p.fetch(ctx, symbols)
+	if err != nil {
+		return fmt.Errorf("polling quotes: %w", err)
+	}
+	for _, quote := range p.diff(quotes, time.Now()) {
+		p.book.Update(quote)
+		p.broker.Publish(ctx, quote)
+	}
+	return nil
+}
+
+// polled is a quote of the REST endpoints with the volume traded since the opening.
+type polled struct {
+	quote  model.WebsocketQuote
+	volume decimal.Decimal
+}
+
+func (p *PollingQuoteSource) fetch(ctx context.Context, symbols []string) ([]polled, error) {
+	var quotes []polled
+	if p.exchange != "" {
+		res, err := p.client.BatchGetQuotesByExchange(ctx, &model.BatchGetQuotesByExchangeParams{Exchange: p.exchange}, p.requestOpts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, quote := range res {
+			if _, ok := slices.BinarySearch(symbols, strings.ToLower(quote.Symbol)); ok {
+				quotes = append(quotes, polled{quote: model.WebsocketQuote{Symbol: quote.Symbol, LastPrice: quote.Price}, volume: quote.Volume})
+			}
+		}
+		return quotes, nil
+	}
+	for batch := range slices.Chunk(symbols, batchQuoteSize) {
+		res, err := p.client.BatchGetQuotes(ctx, &model.BatchGetQuoteParams{Symbols: strings.ToUpper(strings.Join(batch, ","))}, p.requestOpts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, quote := range res {
+			quotes = append(quotes, polled{quote: quote.WebsocketQuote(), volume: quote.Volume})
+		}
+	}
+	return quotes, nil
+}
+
+// diff returns the quotes whose price, volume or timestamp changed since the previous poll of their symbol.
+// Quotes without a timestamp are given the time of the poll.
+func (p *PollingQuoteSource) diff(quotes []polled, now time.Time) []model.WebsocketQuote {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var changed []model.WebsocketQuote
+	for _, q := range quotes {
+		symbol := strings.ToLower(q.quote.Symbol)
+		if !p.subscriptions.subscribed(symbol) {
+			continue
+		}
+		current := polledQuote{price: q.quote.LastPrice, volume: q.volume, timestamp: q.quote.LastUpdated}
+		previous, ok := p.last[symbol]
+		p.last[symbol] = current
+		if ok && previous.price.Equal(current.price) && previous.volume.Equal(current.volume) && previous.timestamp == current.timestamp {
+			continue
+		}
+		quote := q.quote
+		quote.Symbol = symbol
+		if ok && current.volume.GreaterThan(previous.volume) {
+			quote.LastSize = current.volume.Sub(previous.volume)
+		}
+		if quote.LastUpdated == 0 {
+			quote.LastUpdated = now.UnixMilli()
+		}
+		changed = append(changed, quote)
+	}
+	return changed
+}
+
+// forget drops the last polled quotes of the symbols, so that they are emitted on their next poll.
+func (p *PollingQuoteSource) forget(symbols []string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, symbol := range symbols {
+		delete(p.last, symbol)
+	}
+}
+
+// Subscribe adds the symbols to the next polls. Subscriptions are reference counted per symbol like the ones of
+// the WebsocketClient, and each call must be matched by a call to Unsubscribe.
+func (p *PollingQuoteSource) Subscribe(_ context.Context, symbols []string) error {
+	p.subscriptions.acquire(normalizeSymbols(symbols))
+	return nil
+}
+
+// Unsubscribe releases the subscriptions of the symbols. The symbols whose last subscription is released are no
+// longer polled.
+func (p *PollingQuoteSource) Unsubscribe(_ context.Context, symbols []string) error {
+	released := p.subscriptions.release(symbols)
+	p.book.Delete(released...)
+	p.forget(released)
+	return nil
+}
+
+// Subscriptions returns the symbols the source is subscribed to, in lower case and in order.
+func (p *PollingQuoteSource) Subscriptions() []string {
+	return p.subscriptions.symbols()
+}
+
+// Quotes returns the quotes of all the subscribed symbols, like WebsocketClient.Quotes.
+func (p *PollingQuoteSource) Quotes() <-chan model.WebsocketQuote {
+	p.quotesOnce.Do(func() {
+		p.quotesConsumer = p.broker.Consume()
+	})
+	return p.quotesConsumer.Quotes()
+}
+
+// Consume adds a consumer of the quotes polled from now on. The caller should call Close on the consumer once done.
+func (p *PollingQuoteSource) Consume(opts ...ConsumerOption) *QuoteConsumer {
+	return p.broker.Consume(opts...)
+}
+
+// DroppedQuotes returns the number of quotes dropped by the consumers of the source.
+func (p *PollingQuoteSource) DroppedQuotes() uint64 {
+	return p.broker.Dropped()
+}
+
+// QuoteBook returns the latest quote of each subscribed symbol, kept up to date by the source.
+func (p *PollingQuoteSource) QuoteBook() *QuoteBook {
+	return p.book
+}
+
+// WithPollingFailover polls the quotes from the client while the websocket is down: from the moment the connection
+// drops, or the client fails to connect, until it is authenticated again. The polled quotes are published to the
+// same consumers and quote book as the websocket ones, so that consumers are not affected. Symbols subscribed while
+// the client is not connected are polled, and subscribed to once it reconnects. Disconnect stops polling.
+func WithPollingFailover(client *QuoteClient, opts ...PollingOption) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.failover = newPollingQuoteSource(client, wss.logger, wss.subscriptions, wss.broker, wss.book, opts...)
+	}
+}
+
+// failOver starts or stops polling according to the state of the connection.
+func (wss *WebsocketClient) failOver(change ConnectionStateChange) {
+	if wss.failover == nil {
+		return
+	}
+	switch {
+	case change.To == ConnectionStateDegraded, change.To == ConnectionStateClosed && change.Err != nil:
+		if !wss.failover.Running() {
+			wss.logger.Warn("websocket down, polling quotes", slog.Any("error", change.Err))
+		}
+		wss.failover.Start(wss.ctx)
+	case change.To == ConnectionStateAuthenticated, change.To == ConnectionStateClosed:
+		wss.failover.Stop()
+	}
+}
+
+// FailedOver reports whether the client currently polls the quotes because the websocket is down.
+func (wss *WebsocketClient) FailedOver() bool {
+	return wss.failover != nil && wss.failover.Running()
+}
+
+// pollable reports whether the symbols of a subscription that failed with the error are polled in the meantime.
+func (wss *WebsocketClient) pollable(err error) bool {
+	return wss.failover != nil && errors.Is(err, ErrNotConnected)
+}
@@ -0,0 +1,203 @@
+package market
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/client/rest"
+	"go.tradeforge.dev/fmp/fmptest"
+)
+
+// countingTransport counts the requests sent by a client.
+type countingTransport struct {
+	calls atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newPollingTestSource(server *fmptest.Server, opts ...PollingOption) *PollingQuoteSource {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewPollingQuoteSource(&newFixtureTestClient(server).QuoteClient, logger, opts...)
+}
+
+// assertNoQuote asserts that the consumer does not receive a quote for a while.
+func assertNoQuote(t *testing.T, c *QuoteConsumer) {
+	t.Helper()
+	select {
+	case q := <-c.Quotes():
+		t.Fatalf("unexpected quote: %s@%s", q.Symbol, q.LastPrice)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPollingQuoteSource_Poll(t *testing.T) {
+	batchQuote := func(aapl string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`[
+			{"symbol": "AAPL", "price": ` + aapl + `, "volume": 1000, "timestamp": 1735851600},
+			{"symbol": "MSFT", "price": 418.58, "volume": 2000, "timestamp": 1735851600}
+		]`)}
+	}
+	fixtures := fstest.MapFS{"stable/batch-quote.json": batchQuote("243.85")}
+	server := fmptest.NewServer(fmptest.WithFixtures(fixtures))
+	defer server.Close()
+	ctx := context.Background()
+
+	source := newPollingTestSource(server)
+	consumer := source.Consume()
+	defer consumer.Close()
+	require.NoError(t, source.Poll(ctx), "nothing is polled without subscriptions")
+	assert.Zero(t, server.Hits(BatchGetQuotesPath))
+
+	require.NoError(t, source.Subscribe(ctx, []string{"AAPL", "msft"}))
+	require.NoError(t, source.Poll(ctx))
+	assert.Equal(t, []string{"aapl@243.85", "msft@418.58"}, receiveQuotes(t, consumer, 2))
+	quote, ok := source.QuoteBook().Get("aapl")
+	require.True(t, ok)
+	assert.Equal(t, int64(1735851600), quote.LastUpdated)
+
+	require.NoError(t, source.Poll(ctx))
+	assertNoQuote(t, consumer)
+
+	fixtures["stable/batch-quote.json"] = &fstest.MapFile{Data: []byte(`[
+		{"symbol": "AAPL", "price": 244, "volume": 1100, "timestamp": 1735851660},
+		{"symbol": "MSFT", "price": 418.58, "volume": 2000, "timestamp": 1735851600}
+	]`)}
+	require.NoError(t, source.Poll(ctx))
+	select {
+	case quote := <-consumer.Quotes():
+		assert.Equal(t, "aapl", quote.Symbol)
+		assert.Equal(t, "244", quote.LastPrice.String())
+		assert.Equal(t, "100", quote.LastSize.String(), "the last size is the volume traded since the previous poll")
+	case <-time.After(time.Second):
+		t.Fatal("no quote received")
+	}
+	assertNoQuote(t, consumer)
+
+	require.NoError(t, source.Unsubscribe(ctx, []string{"msft"}))
+	assert.Equal(t, []string{"aapl"}, source.Subscriptions())
+	_, ok = source.QuoteBook().Get("msft")
+	assert.False(t, ok)
+
+	server.Fail(BatchGetQuotesPath, fmptest.Fault{StatusCode: http.StatusPaymentRequired, Message: fmptest.MessageRestrictedEndpoint})
+	assert.ErrorContains(t, source.Poll(ctx), "polling quotes")
+}
+
+func TestPollingQuoteSource_Exchange(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	source := newPollingTestSource(server, WithPollingExchange("NYSE"))
+	consumer := source.Consume()
+	defer consumer.Close()
+	require.NoError(t, source.Subscribe(ctx, []string{"jpm"}))
+
+	before := time.Now()
+	require.NoError(t, source.Poll(ctx))
+	assert.Equal(t, []string{"jpm@268.44"}, receiveQuotes(t, consumer, 1))
+	quote, ok := source.QuoteBook().Get("JPM")
+	require.True(t, ok)
+	assert.False(t, quote.Time().Before(before.Truncate(time.Millisecond)), "quotes are timestamped with the time of the poll")
+	assertNoQuote(t, consumer)
+}
+
+func TestPollingQuoteSource_Start(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+
+	// The polls are counted by the client, as the server may still handle a request the client gave up on.
+	transport := &countingTransport{}
+	client := NewHTTPClient(
+		HTTPClientConfig{APIKey: server.APIKey(), BaseURL: server.URL},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		rest.WithTransport(transport),
+	)
+	source := NewPollingQuoteSource(&client.QuoteClient, slog.New(slog.NewTextHandler(io.Discard, nil)), WithPollingInterval(10*time.Millisecond))
+	require.NoError(t, source.Subscribe(context.Background(), []string{"aapl"}))
+	quotes := source.Quotes()
+	source.Start(context.Background())
+	source.Start(context.Background())
+	assert.True(t, source.Running())
+
+	select {
+	case quote := <-quotes:
+		assert.Equal(t, "aapl", quote.Symbol)
+	case <-time.After(time.Second):
+		t.Fatal("no quote received")
+	}
+	require.Eventually(t, func() bool { return transport.calls.Load() > 2 }, time.Second, 5*time.Millisecond)
+	source.Stop()
+	assert.False(t, source.Running())
+	// Stop waits for the poll in progress, so no poll is sent once it returned.
+	polls := transport.calls.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, polls, transport.calls.Load())
+}
+
+func TestWebsocketClient_PollingFailover(t *testing.T) {
+	restServer := fmptest.NewServer()
+	defer restServer.Close()
+	failover := WithPollingFailover(&newFixtureTestClient(restServer).QuoteClient, WithPollingInterval(10*time.Millisecond))
+
+	t.Run("success:connection failed", func(t *testing.T) {
+		client := newWebsocketTestClient(t, restServer, failover)
+		consumer := client.Consume()
+		defer consumer.Close()
+
+		require.Error(t, client.Connect("ws://127.0.0.1:1"))
+		assert.True(t, client.FailedOver())
+		require.NoError(t, client.Subscribe(context.Background(), []string{"aapl"}))
+		assert.Equal(t, []string{"aapl@243.85"}, receiveQuotes(t, consumer, 1))
+
+		require.NoError(t, client.Disconnect())
+		assert.False(t, client.FailedOver())
+	})
+	t.Run("success:connection lost", func(t *testing.T) {
+		server := fmptest.NewServer()
+		stateOpt, changes := recordStates()
+		client := newWebsocketTestClient(t, server, failover, stateOpt, WithReconnectPolicy(fastReconnectPolicy(-1)))
+		consumer := client.Consume()
+		defer consumer.Close()
+
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		require.NoError(t, client.Subscribe(context.Background(), []string{"aapl"}))
+		assert.False(t, client.FailedOver())
+		server.Close()
+
+		awaitState(t, changes, ConnectionStateDegraded)
+		assert.True(t, client.FailedOver())
+		assert.Equal(t, []string{"aapl@243.85"}, receiveQuotes(t, consumer, 1))
+		quote, ok := client.QuoteBook().Get("aapl")
+		require.True(t, ok)
+		assert.Equal(t, "243.85", quote.LastPrice.String())
+
+		require.NoError(t, client.Disconnect())
+		assert.False(t, client.FailedOver())
+	})
+	t.Run("success:reconnected", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		stateOpt, changes := recordStates()
+		client := newWebsocketTestClient(t, server, failover, stateOpt, WithReconnectPolicy(fastReconnectPolicy(-1)))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		awaitState(t, changes, ConnectionStateAuthenticated)
+
+		server.DropWebsockets()
+		awaitState(t, changes, ConnectionStateDegraded)
+		awaitState(t, changes, ConnectionStateAuthenticated)
+		assert.False(t, client.FailedOver())
+	})
+}
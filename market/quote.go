@@ -3,6 +3,7 @@ package market
 import (
 	"context"
 	"fmt"
+	"iter"
 	"net/http"
 
 	"go.tradeforge.dev/fmp/client/rest"
@@ -53,6 +54,14 @@ func (qc *QuoteClient) BatchGetQuotesByExchange(ctx context.Context, params *mod
 	return res, err
 }
 
+// StreamQuotesByExchange is like BatchGetQuotesByExchange but decodes the quotes one at a time as the response
+// arrives, so that the quotes of a whole exchange are not held in memory at once.
+func (qc *QuoteClient) StreamQuotesByExchange(ctx context.Context, params *model.BatchGetQuotesByExchangeParams, opts ...model.RequestOption) iter.Seq2[model.TickerShortQuote, error] {
+	params.Short = false // Ensure we always get full prices, not short ones.
+
+	return rest.Stream[model.TickerShortQuote](ctx, qc.Client, http.MethodGet, BatchGetQuotesByExchangePath, params, opts...)
+}
+
 func (qc *QuoteClient) GetPriceChange(ctx context.Context, params *model.GetPriceChangeParams, opts ...model.RequestOption) (response *model.GetPriceChangeResponse, err error) {
 	var res []model.GetPriceChangeResponse
 	_, err = qc.Call(ctx, http.MethodGet, GetPriceChangePath, params, &res, opts...)
@@ -88,3 +97,9 @@ func (tc *TickerClient) GetBulkPriceEOD(ctx context.Context, params *model.GetBu
 	_, err := tc.Call(ctx, http.MethodGet, GetBulkPriceEODPath, params, &res, opts...)
 	return res, err
 }
+
+// StreamBulkPriceEOD is like GetBulkPriceEOD but decodes the prices one at a time as the response arrives, so that
+// the prices of the whole market are not held in memory at once.
+func (tc *TickerClient) StreamBulkPriceEOD(ctx context.Context, params *model.GetBulkPriceEODParams, opts ...model.RequestOption) iter.Seq2[model.BulkPriceEOD, error] {
+	return rest.Stream[model.BulkPriceEOD](ctx, tc.Client, http.MethodGet, GetBulkPriceEODPath, params, opts...)
+}
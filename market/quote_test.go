@@ -68,6 +68,21 @@ func TestBatchGetQuotesByExchange(t *testing.T) {
 	}
 }
 
+func TestStreamQuotesByExchange(t *testing.T) {
+	client := newTestHTTPClient(t)
+	ctx := context.Background()
+
+	want, err := client.BatchGetQuotesByExchange(ctx, &model.BatchGetQuotesByExchangeParams{Exchange: "NYSE"})
+	require.NoError(t, err)
+
+	var got []model.TickerShortQuote
+	for q, err := range client.StreamQuotesByExchange(ctx, &model.BatchGetQuotesByExchangeParams{Exchange: "NYSE"}) {
+		require.NoError(t, err)
+		got = append(got, q)
+	}
+	assert.Equal(t, want, got)
+}
+
 func TestGetHistoricalBars(t *testing.T) {
 	client := newTestHTTPClient(t)
 	ctx := context.Background()
@@ -147,3 +162,19 @@ func TestGetBulkPriceEOD(t *testing.T) {
 		assert.True(t, p.Open.IsPositive())
 	}
 }
+
+func TestStreamBulkPriceEOD(t *testing.T) {
+	client := newTestHTTPClient(t)
+	ctx := context.Background()
+
+	n := 0
+	for p, err := range client.StreamBulkPriceEOD(ctx, &model.GetBulkPriceEODParams{Date: types.Date("2025-01-02")}) {
+		require.NoError(t, err)
+		assert.NotEmpty(t, p.Symbol)
+		assert.True(t, p.Open.IsPositive())
+		if n++; n == 5 {
+			break
+		}
+	}
+	assert.Equal(t, 5, n)
+}
@@ -0,0 +1,111 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+// batchQuoteSize is the number of symbols requested per call to BatchGetQuotes.
+const batchQuoteSize = 100
+
+// QuoteBook holds the latest quote of each symbol. It is safe for concurrent use.
+//
+// Symbols are case-insensitive. Quotes are only replaced by more recent ones, so that the book can be seeded from
+// the REST endpoints while the websocket updates it.
+type QuoteBook struct {
+	lock   sync.RWMutex
+	quotes map[string]model.WebsocketQuote
+}
+
+// NewQuoteBook returns an empty quote book.
+func NewQuoteBook() *QuoteBook {
+	return &QuoteBook{quotes: make(map[string]model.WebsocketQuote)}
+}
+
+// Update stores the quote unless the book holds a more recent one of its symbol, and reports whether it did.
+func (b *QuoteBook) Update(quote model.WebsocketQuote) bool {
+	symbol := strings.ToLower(quote.Symbol)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if current, ok := b.quotes[symbol]; ok && quote.Time().Before(current.Time()) {
+		return false
+	}
+	b.quotes[symbol] = quote
+	return true
+}
+
+// Get returns the latest quote of the symbol, if any.
+func (b *QuoteBook) Get(symbol string) (model.WebsocketQuote, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	quote, ok := b.quotes[strings.ToLower(symbol)]
+	return quote, ok
+}
+
+// Snapshot returns a copy of the latest quotes by symbol, in lower case.
+func (b *QuoteBook) Snapshot() map[string]model.WebsocketQuote {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return maps.Clone(b.quotes)
+}
+
+// Stale returns the symbols, in lower case and in order, whose latest quote is older than the maximum age.
+func (b *QuoteBook) Stale(maxAge time.Duration) []string {
+	now := time.Now()
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	var stale []string
+	for symbol, quote := range b.quotes {
+		if quote.Age(now) > maxAge {
+			stale = append(stale, symbol)
+		}
+	}
+	slices.Sort(stale)
+	return stale
+}
+
+// Delete removes the quotes of the symbols.
+func (b *QuoteBook) Delete(symbols ...string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, symbol := range symbols {
+		delete(b.quotes, strings.ToLower(symbol))
+	}
+}
+
+// Seed stores the latest quotes of the symbols from the REST endpoint, so that the book has a value before the first
+// update of the websocket. Seeded quotes only have a last price and never replace more recent quotes.
+func (b *QuoteBook) Seed(ctx context.Context, client *QuoteClient, symbols []string, opts ...model.RequestOption) error {
+	for batch := range slices.Chunk(symbols, batchQuoteSize) {
+		quotes, err := client.BatchGetQuotes(ctx, &model.BatchGetQuoteParams{Symbols: strings.ToUpper(strings.Join(batch, ","))}, opts...)
+		if err != nil {
+			return fmt.Errorf("seeding quotes: %w", err)
+		}
+		for _, quote := range quotes {
+			b.Update(quote.WebsocketQuote())
+		}
+	}
+	return nil
+}
+
+// WithQuoteBookSeeding seeds the quote book of the client from the REST endpoint with the symbols it subscribes to,
+// so that they have a value before their first quote. Seeding failures are logged and do not fail the subscription.
+func WithQuoteBookSeeding(client *QuoteClient, opts ...model.RequestOption) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.seedFrom = client
+		wss.seedOpts = opts
+	}
+}
+
+// QuoteBook returns the latest quote of each subscribed symbol, kept up to date by the client. Quotes are removed
+// once their symbol is unsubscribed.
+func (wss *WebsocketClient) QuoteBook() *QuoteBook {
+	return wss.book
+}
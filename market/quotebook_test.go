@@ -0,0 +1,110 @@
+package market
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func TestQuoteBook(t *testing.T) {
+	now := time.Now()
+	book := NewQuoteBook()
+
+	assert.True(t, book.Update(model.WebsocketQuote{
+		Symbol:      "aapl",
+		BidPrice:    decimal.RequireFromString("243.8"),
+		AskPrice:    decimal.RequireFromString("243.9"),
+		LastUpdated: now.UnixMilli(),
+	}))
+	assert.False(t, book.Update(model.WebsocketQuote{Symbol: "AAPL", LastUpdated: now.Add(-time.Second).Unix()}), "older quotes are ignored")
+	assert.True(t, book.Update(model.WebsocketQuote{Symbol: "msft", LastUpdated: now.Add(-time.Minute).UnixMilli()}))
+
+	quote, ok := book.Get("AAPL")
+	require.True(t, ok)
+	assert.Equal(t, "243.85", quote.Mid().String())
+	assert.Equal(t, "0.1", quote.Spread().String())
+	_, ok = book.Get("nvda")
+	assert.False(t, ok)
+
+	snapshot := book.Snapshot()
+	assert.Len(t, snapshot, 2)
+	delete(snapshot, "aapl")
+	_, ok = book.Get("aapl")
+	assert.True(t, ok, "the snapshot is a copy")
+
+	assert.Equal(t, []string{"msft"}, book.Stale(30*time.Second))
+	book.Delete("MSFT")
+	assert.Empty(t, book.Stale(30*time.Second))
+}
+
+func TestQuoteBook_Seed(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+	client := newFixtureTestClient(server)
+
+	t.Run("success:seeded", func(t *testing.T) {
+		book := NewQuoteBook()
+		live := model.WebsocketQuote{Symbol: "msft", LastPrice: decimal.RequireFromString("420"), LastUpdated: time.Now().UnixMilli()}
+		book.Update(live)
+		require.NoError(t, book.Seed(context.Background(), &client.QuoteClient, []string{"aapl", "msft"}))
+
+		quote, ok := book.Get("aapl")
+		require.True(t, ok)
+		assert.True(t, decimal.RequireFromString("243.85").Equal(quote.LastPrice))
+		assert.True(t, quote.Mid().IsZero(), "seeded quotes have no bid nor ask")
+		quote, _ = book.Get("msft")
+		assert.Equal(t, live, quote, "seeding does not replace more recent quotes")
+	})
+	t.Run("error:failed call", func(t *testing.T) {
+		server.Fail(BatchGetQuotesPath, fmptest.Fault{StatusCode: http.StatusPaymentRequired, Message: fmptest.MessageRestrictedEndpoint})
+		err := NewQuoteBook().Seed(context.Background(), &client.QuoteClient, []string{"aapl"})
+		assert.ErrorContains(t, err, "seeding quotes")
+	})
+}
+
+func TestWebsocketClient_QuoteBook(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+	client := newWebsocketTestClient(t, server, WithQuoteBookSeeding(&newFixtureTestClient(server).QuoteClient))
+	require.NoError(t, client.Connect(server.WebsocketURL()))
+	defer func() { assert.NoError(t, client.Disconnect()) }()
+	consumer := client.Consume()
+	defer consumer.Close()
+
+	require.NoError(t, client.Subscribe(context.Background(), []string{"AAPL"}))
+	quote, ok := client.QuoteBook().Get("AAPL")
+	require.True(t, ok, "the book should be seeded before the first quote")
+	assert.True(t, decimal.RequireFromString("243.85").Equal(quote.LastPrice))
+
+	server.PublishQuote(testQuote("aapl", 250))
+	<-consumer.Quotes()
+	quote, _ = client.QuoteBook().Get("AAPL")
+	assert.True(t, decimal.NewFromInt(250).Equal(quote.LastPrice))
+
+	require.NoError(t, client.Unsubscribe(context.Background(), []string{"AAPL"}))
+	assert.Empty(t, client.QuoteBook().Snapshot())
+
+	// A slow seed does not hold up the other subscriptions, and the symbols unsubscribed meanwhile are not kept.
+	server.Fail(BatchGetQuotesPath, fmptest.Fault{Latency: 200 * time.Millisecond, Times: 1})
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- client.Subscribe(context.Background(), []string{"MSFT"})
+	}()
+	require.Eventually(t, func() bool { return len(client.Subscriptions()) == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, client.Unsubscribe(context.Background(), []string{"MSFT"}))
+	select {
+	case <-subscribed:
+		t.Fatal("the subscription should still be seeding")
+	default:
+	}
+	require.NoError(t, <-subscribed)
+	assert.Empty(t, client.QuoteBook().Snapshot())
+}
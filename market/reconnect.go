@@ -0,0 +1,142 @@
+package market
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DefaultReconnectInitialInterval = 500 * time.Millisecond
+	DefaultReconnectMaxInterval     = 30 * time.Second
+	DefaultReconnectMultiplier      = 2.0
+	DefaultReconnectJitter          = 0.5
+)
+
+// ConnectionState is the state of the connection of the WebsocketClient.
+type ConnectionState string
+
+const (
+	// ConnectionStateConnecting is the state while the client dials and authenticates.
+	ConnectionStateConnecting ConnectionState = "connecting"
+
+	// ConnectionStateAuthenticated is the state while the client is logged in and receives the subscribed symbols.
+	ConnectionStateAuthenticated ConnectionState = "authenticated"
+
+	// ConnectionStateDegraded is the state after the connection dropped and while the client waits to reconnect.
+	ConnectionStateDegraded ConnectionState = "degraded"
+
+	// ConnectionStateClosed is the state after the client disconnected or gave up reconnecting.
+	ConnectionStateClosed ConnectionState = "closed"
+)
+
+// ConnectionStateChange is a transition of the connection state of the WebsocketClient.
+type ConnectionStateChange struct {
+	From ConnectionState
+	To   ConnectionState
+
+	// Attempt is the number of the reconnection attempt, starting at 1. It is zero for the initial connection.
+	Attempt int
+
+	// Err is the error that caused the transition, if any.
+	Err error
+
+	Time time.Time
+}
+
+// ReconnectPolicy defines how the WebsocketClient reconnects after its connection dropped. Once reconnected,
+// the client authenticates again and resubscribes all the symbols it was subscribed to.
+type ReconnectPolicy struct {
+	// MaxAttempts is the maximum number of reconnection attempts in a row. Zero disables reconnection and
+	// a negative value retries until the client is disconnected.
+	MaxAttempts int
+
+	// InitialInterval is the backoff before the first attempt.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the backoff between two attempts.
+	MaxInterval time.Duration
+
+	// Multiplier grows the backoff after each attempt.
+	Multiplier float64
+
+	// Jitter randomizes the backoff by the given factor, e.g. 0.5 yields a backoff between 50% and 150% of the interval.
+	Jitter float64
+
+	// MaxElapsedTime stops reconnecting once the connection has been down for longer than that. Zero means no limit.
+	MaxElapsedTime time.Duration
+}
+
+// DefaultReconnectPolicy returns the reconnect policy used by the client unless configured otherwise.
+// It retries until the client is disconnected.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		MaxAttempts:     -1,
+		InitialInterval: DefaultReconnectInitialInterval,
+		MaxInterval:     DefaultReconnectMaxInterval,
+		Multiplier:      DefaultReconnectMultiplier,
+		Jitter:          DefaultReconnectJitter,
+	}
+}
+
+// WithReconnectPolicy replaces the default reconnect policy of the client.
+func WithReconnectPolicy(policy ReconnectPolicy) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.reconnectPolicy = policy
+	}
+}
+
+// WithConnectionStateHandler calls the handler on every transition of the connection state. The handler is called
+// synchronously from the goroutine making the transition and must not block.
+func WithConnectionStateHandler(handler func(change ConnectionStateChange)) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.onStateChange = handler
+	}
+}
+
+// backoff returns the time to wait before the given reconnection attempt, starting at 1, and whether the attempt
+// should be made at all given the time the connection has been down.
+func (p ReconnectPolicy) backoff(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	if p.MaxAttempts >= 0 && attempt > p.MaxAttempts {
+		return 0, false
+	}
+
+	interval := float64(p.InitialInterval)
+	for range attempt - 1 {
+		interval *= p.Multiplier
+		if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+			break
+		}
+	}
+	if p.MaxInterval > 0 {
+		interval = min(interval, float64(p.MaxInterval))
+	}
+	if p.Jitter > 0 {
+		delta := p.Jitter * interval
+		interval = interval - delta + rand.Float64()*2*delta //nolint:gosec // jitter does not need a secure source
+	}
+	wait := time.Duration(interval)
+
+	if p.MaxElapsedTime > 0 && elapsed+wait > p.MaxElapsedTime {
+		return 0, false
+	}
+	return wait, true
+}
+
+// State returns the current state of the connection.
+func (wss *WebsocketClient) State() ConnectionState {
+	wss.stateLock.Lock()
+	defer wss.stateLock.Unlock()
+	return wss.state
+}
+
+func (wss *WebsocketClient) setState(to ConnectionState, attempt int, err error) {
+	wss.stateLock.Lock()
+	change := ConnectionStateChange{From: wss.state, To: to, Attempt: attempt, Err: err, Time: time.Now()}
+	wss.state = to
+	wss.stateLock.Unlock()
+
+	if wss.onStateChange != nil {
+		wss.onStateChange(change)
+	}
+	wss.failOver(change)
+}
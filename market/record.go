@@ -0,0 +1,135 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+// RecordedFrame is a raw message received by the WebsocketClient, as written by WithRecording.
+type RecordedFrame struct {
+	// Time is the time the message was received.
+	Time time.Time           `json:"t"`
+	Feed model.WebsocketFeed `json:"f"`
+	Raw  json.RawMessage     `json:"m"`
+}
+
+// WithRecording writes every message the client receives, heartbeats and acknowledgments included, to the writer
+// as one JSON RecordedFrame per line. The session can then be replayed with Replay. The writer is not closed by the
+// client; wrap it in a gzip.Writer for a more compact log. Write failures are logged and do not drop the connection.
+func WithRecording(w io.Writer) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.recorder = &recorder{encoder: json.NewEncoder(w)}
+	}
+}
+
+type recorder struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+func (r *recorder) record(logger *slog.Logger, feed model.WebsocketFeed, raw json.RawMessage) {
+	if r == nil {
+		return
+	}
+	frame := RecordedFrame{Time: time.Now(), Feed: feed, Raw: raw}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.encoder.Encode(frame); err != nil {
+		logger.Warn("recording websocket message", slog.Any("error", err))
+	}
+}
+
+// ReadFrames decodes the frames written by WithRecording, in order, until the end of the reader or the first error.
+func ReadFrames(r io.Reader) iter.Seq2[RecordedFrame, error] {
+	return func(yield func(RecordedFrame, error) bool) {
+		decoder := json.NewDecoder(r)
+		for {
+			var frame RecordedFrame
+			err := decoder.Decode(&frame)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(RecordedFrame{}, fmt.Errorf("decoding recorded frame: %w", err))
+				return
+			}
+			if !yield(frame, nil) {
+				return
+			}
+		}
+	}
+}
+
+type replayConfig struct {
+	speed float64
+}
+
+// ReplayOption changes how Replay paces the recorded frames.
+type ReplayOption func(c *replayConfig)
+
+// WithReplaySpeed replays the frames at the multiple of the speed they were received at, e.g. 10 for ten times
+// faster. Zero or less replays them as fast as possible. Frames are replayed at the speed they were received at
+// by default.
+func WithReplaySpeed(speed float64) ReplayOption {
+	return func(c *replayConfig) {
+		c.speed = speed
+	}
+}
+
+// Replay feeds the frames of a session recorded with WithRecording to the client as if it received them, until the
+// end of the reader or the context is done. The quotes are published to Quotes, the consumers, the events and the
+// quote book like live ones, so that a session can be reproduced without network. The client should not be
+// connected while replaying. Acknowledgments are ignored.
+func (wss *WebsocketClient) Replay(ctx context.Context, r io.Reader, opts ...ReplayOption) error {
+	config := replayConfig{speed: 1}
+	for _, o := range opts {
+		o(&config)
+	}
+
+	var first time.Time
+	start := time.Now()
+	for frame, err := range ReadFrames(r) {
+		if err != nil {
+			return err
+		}
+		if first.IsZero() {
+			first = frame.Time
+		}
+		if config.speed > 0 {
+			offset := time.Duration(float64(frame.Time.Sub(first)) / config.speed)
+			if err := sleep(ctx, time.Until(start.Add(offset))); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := wss.processMessage(ctx, nil, frame.Feed, frame.Raw); err != nil {
+			return fmt.Errorf("replaying frame of %s: %w", frame.Time.Format(time.RFC3339Nano), err)
+		}
+	}
+	return nil
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
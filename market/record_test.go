@@ -0,0 +1,151 @@
+package market
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+)
+
+// recordedSession returns the frames of a session with the quotes, received the interval apart.
+func recordedSession(t *testing.T, interval time.Duration, quotes ...model.WebsocketQuote) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	received := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+	require.NoError(t, encoder.Encode(RecordedFrame{Time: received, Feed: model.WebsocketFeedStock, Raw: json.RawMessage(`{"event":"login","status":200,"message":"Authenticated"}`)}))
+	for _, quote := range quotes {
+		received = received.Add(interval)
+		raw, err := json.Marshal(struct {
+			model.WebsocketQuote
+			Type model.WebsocketMessageType `json:"type"`
+		}{quote, model.WebsocketMessageTypeQuote})
+		require.NoError(t, err)
+		require.NoError(t, encoder.Encode(RecordedFrame{Time: received, Feed: model.WebsocketFeedStock, Raw: raw}))
+	}
+	return &buf
+}
+
+func newReplayTestClient(t *testing.T) *WebsocketClient {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client, err := NewWebsocketClient(context.Background(), WebsocketClientConfig{APIKey: "replay"}, logger)
+	require.NoError(t, err)
+	return client
+}
+
+func TestWebsocketClient_Record(t *testing.T) {
+	server := fmptest.NewServer()
+	defer server.Close()
+	var buf bytes.Buffer
+	client := newWebsocketTestClient(t, server, WithRecording(&buf))
+	consumer := client.Consume()
+	defer consumer.Close()
+	require.NoError(t, client.Connect(server.WebsocketURL()))
+	require.NoError(t, client.Subscribe(context.Background(), []string{"aapl"}))
+	server.PublishQuote(testQuote("aapl", 243))
+	server.PublishQuote(testQuote("aapl", 244))
+	live := receiveQuotes(t, consumer, 2)
+	require.NoError(t, client.Disconnect())
+
+	var events []model.WebsocketEventName
+	var received []model.WebsocketQuote
+	var last time.Time
+	recording := buf.Bytes()
+	for frame, err := range ReadFrames(bytes.NewReader(recording)) {
+		require.NoError(t, err)
+		assert.Equal(t, model.WebsocketFeedStock, frame.Feed)
+		assert.False(t, frame.Time.Before(last), "frames are recorded in order")
+		last = frame.Time
+		var msg model.WebsocketMesssage
+		require.NoError(t, json.Unmarshal(frame.Raw, &msg))
+		if msg.Event != "" {
+			events = append(events, msg.Event)
+			continue
+		}
+		var quote model.WebsocketQuote
+		require.NoError(t, json.Unmarshal(frame.Raw, &quote))
+		received = append(received, quote)
+	}
+	assert.Subset(t, events, []model.WebsocketEventName{model.WebsocketEventNameLogin, model.WebsocketEventNameSubscribe})
+	require.Len(t, received, 2)
+	assert.Equal(t, "244", received[1].LastPrice.String())
+
+	t.Run("success:replayed", func(t *testing.T) {
+		replay := newReplayTestClient(t)
+		consumer := replay.Consume()
+		defer consumer.Close()
+		require.NoError(t, replay.Replay(context.Background(), bytes.NewReader(recording), WithReplaySpeed(0)))
+		assert.Equal(t, live, receiveQuotes(t, consumer, 2))
+		quote, ok := replay.QuoteBook().Get("AAPL")
+		require.True(t, ok)
+		assert.Equal(t, "244", quote.LastPrice.String())
+	})
+}
+
+func TestWebsocketClient_Replay(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []ReplayOption
+		interval time.Duration
+		min      time.Duration
+		max      time.Duration
+	}{
+		{
+			name:     "success:real speed",
+			interval: 50 * time.Millisecond,
+			min:      100 * time.Millisecond,
+			max:      time.Second,
+		},
+		{
+			name:     "success:faster",
+			opts:     []ReplayOption{WithReplaySpeed(10)},
+			interval: 500 * time.Millisecond,
+			min:      100 * time.Millisecond,
+			max:      500 * time.Millisecond,
+		},
+		{
+			name:     "success:as fast as possible",
+			opts:     []ReplayOption{WithReplaySpeed(0)},
+			interval: time.Hour,
+			max:      500 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newReplayTestClient(t)
+			consumer := client.Consume()
+			defer consumer.Close()
+
+			start := time.Now()
+			session := recordedSession(t, tt.interval, testQuote("aapl", 243), testQuote("msft", 420))
+			require.NoError(t, client.Replay(context.Background(), session, tt.opts...))
+			elapsed := time.Since(start)
+			assert.GreaterOrEqual(t, elapsed, tt.min)
+			assert.Less(t, elapsed, tt.max)
+
+			assert.Equal(t, []string{"aapl@243", "msft@420"}, receiveQuotes(t, consumer, 2))
+		})
+	}
+
+	t.Run("error:malformed frame", func(t *testing.T) {
+		err := newReplayTestClient(t).Replay(context.Background(), strings.NewReader("{\n"))
+		assert.ErrorContains(t, err, "decoding recorded frame")
+	})
+	t.Run("error:context done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		session := recordedSession(t, time.Hour, testQuote("aapl", 243))
+		assert.ErrorIs(t, newReplayTestClient(t).Replay(ctx, session), context.DeadlineExceeded)
+	})
+}
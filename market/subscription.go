@@ -0,0 +1,115 @@
+package market
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultSubscriptionBatchSize is the number of symbols sent per subscription message unless configured otherwise.
+const DefaultSubscriptionBatchSize = 100
+
+// WithSubscriptionBatchSize sets the maximum number of symbols sent per subscribe or unsubscribe message.
+// Larger symbol lists are split into several messages, each acknowledged on its own.
+// It defaults to DefaultSubscriptionBatchSize.
+func WithSubscriptionBatchSize(n int) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.subscriptionBatchSize = n
+	}
+}
+
+// subscriptionRegistry counts the references to the symbols the client is subscribed to. Symbols are case-insensitive,
+// like they are for FMP, and kept in lower case.
+type subscriptionRegistry struct {
+	lock sync.Mutex
+	refs map[string]int
+}
+
+func newSubscriptionRegistry() *subscriptionRegistry {
+	return &subscriptionRegistry{refs: make(map[string]int)}
+}
+
+// partition splits the symbols, once normalized and deduplicated, into the ones that are already subscribed and
+// the ones that are not.
+func (r *subscriptionRegistry) partition(symbols []string) (live, missing []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, symbol := range normalizeSymbols(symbols) {
+		if r.refs[symbol] > 0 {
+			live = append(live, symbol)
+		} else {
+			missing = append(missing, symbol)
+		}
+	}
+	return live, missing
+}
+
+// acquire adds a reference to each of the symbols.
+func (r *subscriptionRegistry) acquire(symbols []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, symbol := range symbols {
+		r.refs[symbol]++
+	}
+}
+
+// release removes a reference from each of the symbols, once normalized and deduplicated, and returns the symbols
+// whose last reference was removed. Symbols without a reference are ignored.
+func (r *subscriptionRegistry) release(symbols []string) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var released []string
+	for _, symbol := range normalizeSymbols(symbols) {
+		switch r.refs[symbol] {
+		case 0:
+		case 1:
+			delete(r.refs, symbol)
+			released = append(released, symbol)
+		default:
+			r.refs[symbol]--
+		}
+	}
+	return released
+}
+
+// reset removes all the symbols.
+func (r *subscriptionRegistry) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	clear(r.refs)
+}
+
+// subscribed reports whether the symbol, in lower case, is subscribed.
+func (r *subscriptionRegistry) subscribed(symbol string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.refs[symbol] > 0
+}
+
+// symbols returns the subscribed symbols in order.
+func (r *subscriptionRegistry) symbols() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return slices.Sorted(maps.Keys(r.refs))
+}
+
+// normalizeSymbols returns the symbols in lower case, without duplicates and in their original order.
+func normalizeSymbols(symbols []string) []string {
+	normalized := make([]string, 0, len(symbols))
+	seen := make(map[string]struct{}, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToLower(strings.TrimSpace(symbol))
+		if _, ok := seen[symbol]; ok || symbol == "" {
+			continue
+		}
+		seen[symbol] = struct{}{}
+		normalized = append(normalized, symbol)
+	}
+	return normalized
+}
+
+// Subscriptions returns the symbols the client is subscribed to, in lower case and in order.
+func (wss *WebsocketClient) Subscriptions() []string {
+	return wss.subscriptions.symbols()
+}
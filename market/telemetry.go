@@ -0,0 +1,108 @@
+package market
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"go.tradeforge.dev/fmp/model"
+)
+
+const instrumentationName = "go.tradeforge.dev/fmp/market"
+
+const attrMessageKind = attribute.Key("fmp.websocket.message")
+
+// WebsocketOption changes the configuration of the WebsocketClient.
+type WebsocketOption func(wss *WebsocketClient)
+
+// WithWebsocketMeterProvider records connection state, message and quote lag metrics of the websocket client.
+func WithWebsocketMeterProvider(provider metric.MeterProvider) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		m, err := newWebsocketMetrics(provider.Meter(instrumentationName))
+		if err != nil {
+			wss.logger.Warn("websocket metrics disabled", slog.Any("error", err))
+			return
+		}
+		wss.metrics = m
+	}
+}
+
+type websocketMetrics struct {
+	connections metric.Int64UpDownCounter
+	messages    metric.Int64Counter
+	quoteLag    metric.Float64Histogram
+	dropped     metric.Int64Counter
+}
+
+func newWebsocketMetrics(meter metric.Meter) (*websocketMetrics, error) {
+	connections, err := meter.Int64UpDownCounter(
+		"fmp.websocket.connections",
+		metric.WithDescription("Number of open FMP websocket connections."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := meter.Int64Counter(
+		"fmp.websocket.messages",
+		metric.WithDescription("Number of messages received from the FMP websocket by event or message type."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	quoteLag, err := meter.Float64Histogram(
+		"fmp.websocket.quote.lag",
+		metric.WithDescription("Time between the last update of a quote and its receipt."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	dropped, err := meter.Int64Counter(
+		"fmp.websocket.quotes.dropped",
+		metric.WithDescription("Number of quotes dropped or conflated by slow consumers."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &websocketMetrics{
+		connections: connections,
+		messages:    messages,
+		quoteLag:    quoteLag,
+		dropped:     dropped,
+	}, nil
+}
+
+func (m *websocketMetrics) recordConnection(ctx context.Context, delta int64) {
+	if m == nil {
+		return
+	}
+	m.connections.Add(ctx, delta)
+}
+
+func (m *websocketMetrics) recordMessage(ctx context.Context, msg model.WebsocketMesssage) {
+	if m == nil {
+		return
+	}
+	kind := string(msg.Event)
+	if msg.Type != nil {
+		kind = string(*msg.Type)
+	}
+	m.messages.Add(ctx, 1, metric.WithAttributes(attrMessageKind.String(kind)))
+}
+
+func (m *websocketMetrics) recordQuote(ctx context.Context, quote model.WebsocketQuote) {
+	if m == nil || quote.LastUpdated == 0 {
+		return
+	}
+	m.quoteLag.Record(ctx, time.Since(quote.Time()).Seconds())
+}
+
+func (m *websocketMetrics) recordDroppedQuote(model.WebsocketQuote) {
+	if m == nil {
+		return
+	}
+	m.dropped.Add(context.Background(), 1)
+}
@@ -3,8 +3,6 @@ package market
 import (
 	"bytes"
 	"context"
-	"encoding/csv"
-	"fmt"
 	"net/http"
 
 	"go.tradeforge.dev/fmp/client/rest"
@@ -55,6 +53,44 @@ func (tc *TickerClient) GetCompanyProfile(ctx context.Context, params *model.Get
 }
 
 func (tc *TickerClient) BulkGetCompanyProfile(ctx context.Context, params *model.BulkGetCompanyProfilesParams, opts ...model.RequestOption) ([]model.BulkCompanyProfileResponse, error) {
+	body, err := tc.getBulkCompanyProfileCSV(ctx, params, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.BulkCompanyProfileResponse, 0)
+	if len(body) == 0 {
+		return res, nil
+	}
+	for p, err := range readCompanyProfiles(bytes.NewReader(body)) {
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// BulkGetCompanyProfileLenient is like BulkGetCompanyProfile but skips the rows that cannot be decoded and returns
+// them next to the decoded ones. It fails with ErrTooManySkippedRows, together with the partial result, once more
+// rows are skipped than the policy allows.
+func (tc *TickerClient) BulkGetCompanyProfileLenient(
+	ctx context.Context,
+	params *model.BulkGetCompanyProfilesParams,
+	policy model.SkipPolicy,
+	opts ...model.RequestOption,
+) (*model.PartialResult[model.BulkCompanyProfileResponse], error) {
+	body, err := tc.getBulkCompanyProfileCSV(ctx, params, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return &model.PartialResult[model.BulkCompanyProfileResponse]{}, nil
+	}
+	return decodeLenient[model.BulkCompanyProfileResponse](bytes.NewReader(body), policy)
+}
+
+// getBulkCompanyProfileCSV returns the CSV body of a part. It is empty if there is no more data to fetch.
+func (tc *TickerClient) getBulkCompanyProfileCSV(ctx context.Context, params *model.BulkGetCompanyProfilesParams, opts ...model.RequestOption) ([]byte, error) {
 	r, err := tc.Call(
 		ctx,
 		http.MethodGet,
@@ -69,30 +105,9 @@ func (tc *TickerClient) BulkGetCompanyProfile(ctx context.Context, params *model
 	}
 	if r.StatusCode() == http.StatusBadRequest {
 		// Return empty response if no data is returned. FMP sends a 400 status code when there is no more data to fetch.
-		return []model.BulkCompanyProfileResponse{}, nil
-	}
-	csvReader := csv.NewReader(bytes.NewReader(r.Body()))
-	h, err := csvReader.Read()
-	if err != nil {
-		return nil, fmt.Errorf("reading header: %w", err)
-	}
-	d, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("reading records: %w", err)
+		return nil, nil
 	}
-	res := make([]model.BulkCompanyProfileResponse, 0, len(d))
-	for _, record := range d {
-		if len(record) != len(h) {
-			return nil, fmt.Errorf("invalid record length: expected %d, got %d", len(h), len(record))
-		}
-		p, err := model.ParseCompanyProfileCSVRecord(h, record)
-		if err != nil {
-			return nil, fmt.Errorf("parsing record: %w", err)
-		}
-		res = append(res, *p)
-	}
-
-	return res, err
+	return r.Body(), nil
 }
 
 func (tc *TickerClient) GetFinancialKeyMetricsTTM(ctx context.Context, params *model.GetFinancialKeyMetricsTTMParams, opts ...model.RequestOption) (model.GetFinancialKeyMetricsTTMResponse, error) {
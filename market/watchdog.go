@@ -0,0 +1,226 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// watchdogChecksPerTimeout is the number of times the watchdog checks the feed within its shortest timeout.
+	watchdogChecksPerTimeout = 4
+
+	minWatchdogInterval = time.Millisecond
+)
+
+// ErrStaleFeed is the error of the connections dropped by the watchdog.
+var ErrStaleFeed = errors.New("websocket feed stale")
+
+// StalenessKind is what went stale in the feed of the WebsocketClient.
+type StalenessKind string
+
+const (
+	StalenessHeartbeat StalenessKind = "heartbeat"
+	StalenessQuote     StalenessKind = "quote"
+)
+
+// StalenessEvent reports that the heartbeats or the quotes of a symbol stopped coming in.
+type StalenessEvent struct {
+	Kind StalenessKind
+
+	// Symbol is the stale symbol, in lower case. It is empty for heartbeats.
+	Symbol string
+
+	// LastSeen is the time the last heartbeat or quote was received. It is zero if none was received yet.
+	LastSeen time.Time
+
+	// Elapsed is the time since the last heartbeat or quote, or since the connection was established if it is more recent.
+	Elapsed time.Duration
+
+	// Reconnect reports whether the watchdog dropped the connection to reconnect.
+	Reconnect bool
+
+	Time time.Time
+}
+
+// WatchdogConfig defines when the WebsocketClient considers its feed stale.
+type WatchdogConfig struct {
+	// HeartbeatTimeout is the time without heartbeat after which the feed is stale. Zero disables the check.
+	HeartbeatTimeout time.Duration
+
+	// QuoteTimeout is the time without quote after which a subscribed symbol is stale. Zero disables the check.
+	QuoteTimeout time.Duration
+
+	// ReconnectTimeout is the time without heartbeat after which the connection is dropped, so that the client
+	// reconnects according to its ReconnectPolicy. Zero disables it.
+	ReconnectTimeout time.Duration
+
+	// OnStale is called once each time the heartbeats or the quotes of a symbol go stale, and when the watchdog drops
+	// the connection. It is called from the goroutine of the watchdog and must not block.
+	OnStale func(event StalenessEvent)
+}
+
+// WithWatchdog watches the heartbeats and the quotes of the subscribed symbols and reports them once they go stale.
+// The feed is not watched unless configured.
+func WithWatchdog(config WatchdogConfig) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.watchdog.config = config
+	}
+}
+
+// watchdog tracks the last heartbeat and the last quote of each symbol.
+type watchdog struct {
+	config WatchdogConfig
+
+	lock           sync.Mutex
+	connected      time.Time
+	lastHeartbeat  time.Time
+	heartbeatStale bool
+	lastQuotes     map[string]time.Time
+	staleSymbols   map[string]struct{}
+}
+
+func newWatchdog() *watchdog {
+	return &watchdog{
+		lastQuotes:   make(map[string]time.Time),
+		staleSymbols: make(map[string]struct{}),
+	}
+}
+
+func (w *watchdog) enabled() bool {
+	return w.config.HeartbeatTimeout > 0 || w.config.QuoteTimeout > 0 || w.config.ReconnectTimeout > 0
+}
+
+// interval returns the time between two checks of the feed.
+func (w *watchdog) interval() time.Duration {
+	shortest := time.Duration(0)
+	for _, timeout := range []time.Duration{w.config.HeartbeatTimeout, w.config.QuoteTimeout, w.config.ReconnectTimeout} {
+		if timeout > 0 && (shortest == 0 || timeout < shortest) {
+			shortest = timeout
+		}
+	}
+	return max(shortest/watchdogChecksPerTimeout, minWatchdogInterval)
+}
+
+// reset starts watching a new connection, which is given the full timeouts to deliver heartbeats and quotes.
+func (w *watchdog) reset(now time.Time) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.connected = now
+	w.heartbeatStale = false
+	clear(w.staleSymbols)
+}
+
+func (w *watchdog) heartbeat(now time.Time) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.lastHeartbeat = now
+	w.heartbeatStale = false
+}
+
+func (w *watchdog) quote(symbol string, now time.Time) {
+	symbol = strings.ToLower(symbol)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.lastQuotes[symbol] = now
+	delete(w.staleSymbols, symbol)
+}
+
+// forget stops tracking the quotes of the symbols, e.g. once they are unsubscribed.
+func (w *watchdog) forget(symbols []string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, symbol := range symbols {
+		delete(w.lastQuotes, symbol)
+		delete(w.staleSymbols, symbol)
+	}
+}
+
+// check returns the events of what went stale since the previous check and whether the connection should be dropped.
+func (w *watchdog) check(now time.Time, symbols []string) ([]StalenessEvent, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var events []StalenessEvent
+	elapsed := now.Sub(later(w.lastHeartbeat, w.connected))
+	reconnect := w.config.ReconnectTimeout > 0 && elapsed > w.config.ReconnectTimeout
+	if reconnect || (w.config.HeartbeatTimeout > 0 && elapsed > w.config.HeartbeatTimeout && !w.heartbeatStale) {
+		w.heartbeatStale = true
+		events = append(events, StalenessEvent{
+			Kind:      StalenessHeartbeat,
+			LastSeen:  w.lastHeartbeat,
+			Elapsed:   elapsed,
+			Reconnect: reconnect,
+			Time:      now,
+		})
+	}
+	if w.config.QuoteTimeout <= 0 {
+		return events, reconnect
+	}
+	for _, symbol := range symbols {
+		if _, ok := w.staleSymbols[symbol]; ok {
+			continue
+		}
+		lastSeen := w.lastQuotes[symbol]
+		if elapsed := now.Sub(later(lastSeen, w.connected)); elapsed > w.config.QuoteTimeout {
+			w.staleSymbols[symbol] = struct{}{}
+			events = append(events, StalenessEvent{Kind: StalenessQuote, Symbol: symbol, LastSeen: lastSeen, Elapsed: elapsed, Time: now})
+		}
+	}
+	return events, reconnect
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// watch checks the feed of the connection until the context is done, and drops the connection once it is stale
+// for longer than the reconnect timeout.
+func (wss *WebsocketClient) watch(ctx context.Context, conn *websocketConnection) {
+	ticker := time.NewTicker(wss.watchdog.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			events, reconnect := wss.watchdog.check(now, wss.subscriptions.symbols())
+			for _, event := range events {
+				wss.logger.Warn("websocket feed stale",
+					slog.String("kind", string(event.Kind)),
+					slog.String("symbol", event.Symbol),
+					slog.Duration("elapsed", event.Elapsed),
+					slog.Bool("reconnect", event.Reconnect),
+				)
+				if wss.watchdog.config.OnStale != nil {
+					wss.watchdog.config.OnStale(event)
+				}
+			}
+			if reconnect {
+				_ = conn.abort(ErrStaleFeed)
+				return
+			}
+		}
+	}
+}
+
+// LastHeartbeat returns the time the last heartbeat was received. It is zero if none was received yet.
+func (wss *WebsocketClient) LastHeartbeat() time.Time {
+	wss.watchdog.lock.Lock()
+	defer wss.watchdog.lock.Unlock()
+	return wss.watchdog.lastHeartbeat
+}
+
+// LastQuote returns the time the last quote of the symbol was received, if any.
+func (wss *WebsocketClient) LastQuote(symbol string) (time.Time, bool) {
+	wss.watchdog.lock.Lock()
+	defer wss.watchdog.lock.Unlock()
+	t, ok := wss.watchdog.lastQuotes[strings.ToLower(symbol)]
+	return t, ok
+}
@@ -0,0 +1,149 @@
+package market
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+)
+
+// recordStaleness returns the watchdog option recording the staleness events and the channel receiving them.
+func recordStaleness(config WatchdogConfig) (WebsocketOption, <-chan StalenessEvent) {
+	events := make(chan StalenessEvent, 64)
+	config.OnStale = func(event StalenessEvent) {
+		events <- event
+	}
+	return WithWatchdog(config), events
+}
+
+func awaitStaleness(t *testing.T, events <-chan StalenessEvent, match func(event StalenessEvent) bool) StalenessEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if match(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("no matching staleness event")
+		}
+	}
+}
+
+func TestWebsocketClient_Watchdog(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success:paused feed reconnects", func(t *testing.T) {
+		server := fmptest.NewServer(fmptest.WithHeartbeatInterval(5 * time.Millisecond))
+		defer server.Close()
+		watchdogOpt, events := recordStaleness(WatchdogConfig{HeartbeatTimeout: 50 * time.Millisecond, ReconnectTimeout: 150 * time.Millisecond})
+		stateOpt, changes := recordStates()
+		client := newWebsocketTestClient(t, server, watchdogOpt, stateOpt, WithReconnectPolicy(fastReconnectPolicy(-1)))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		require.NoError(t, client.Subscribe(ctx, []string{"AAPL"}))
+		require.Eventually(t, func() bool { return !client.LastHeartbeat().IsZero() }, time.Second, time.Millisecond)
+
+		server.PauseWebsockets()
+		stale := awaitStaleness(t, events, func(event StalenessEvent) bool { return event.Kind == StalenessHeartbeat })
+		assert.False(t, stale.Reconnect)
+		assert.False(t, stale.LastSeen.IsZero())
+		assert.Greater(t, stale.Elapsed, 50*time.Millisecond)
+		reconnect := awaitStaleness(t, events, func(event StalenessEvent) bool { return event.Reconnect })
+		assert.Greater(t, reconnect.Elapsed, 150*time.Millisecond)
+
+		degraded := awaitState(t, changes, ConnectionStateDegraded)
+		assert.ErrorIs(t, degraded.Err, ErrStaleFeed)
+		awaitState(t, changes, ConnectionStateAuthenticated)
+		assert.Equal(t, 1, server.Subscribers("AAPL"), "the new connection should be resubscribed")
+		assert.Eventually(t, func() bool { return time.Since(client.LastHeartbeat()) < 50*time.Millisecond }, time.Second, time.Millisecond)
+	})
+	t.Run("success:stale symbol", func(t *testing.T) {
+		server := fmptest.NewServer(fmptest.WithHeartbeatInterval(5 * time.Millisecond))
+		defer server.Close()
+		watchdogOpt, events := recordStaleness(WatchdogConfig{QuoteTimeout: 50 * time.Millisecond, ReconnectTimeout: time.Second})
+		client := newWebsocketTestClient(t, server, watchdogOpt)
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		consumer := client.Consume(WithBackpressure(BackpressureDropOldest))
+		defer consumer.Close()
+		require.NoError(t, client.Subscribe(ctx, []string{"AAPL", "MSFT"}))
+
+		// MSFT keeps quoting while AAPL goes quiet.
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			ticker := time.NewTicker(5 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					server.PublishQuote(testQuote("msft", 1))
+				}
+			}
+		}()
+
+		stale := awaitStaleness(t, events, func(StalenessEvent) bool { return true })
+		assert.Equal(t, StalenessQuote, stale.Kind)
+		assert.Equal(t, "aapl", stale.Symbol)
+		assert.True(t, stale.LastSeen.IsZero())
+		assert.False(t, stale.Reconnect)
+		_, ok := client.LastQuote("MSFT")
+		assert.True(t, ok)
+
+		server.PublishQuote(testQuote("aapl", 1))
+		require.Eventually(t, func() bool { _, ok := client.LastQuote("AAPL"); return ok }, time.Second, time.Millisecond)
+		stale = awaitStaleness(t, events, func(StalenessEvent) bool { return true })
+		assert.Equal(t, "aapl", stale.Symbol, "a symbol goes stale again after a new quote")
+		assert.False(t, stale.LastSeen.IsZero())
+
+		require.NoError(t, client.Unsubscribe(ctx, []string{"AAPL"}))
+		_, ok = client.LastQuote("AAPL")
+		assert.False(t, ok, "the quotes of unsubscribed symbols are forgotten")
+	})
+}
+
+func TestWatchdog_Check(t *testing.T) {
+	start := time.Now()
+	w := newWatchdog()
+	w.config = WatchdogConfig{HeartbeatTimeout: time.Second, QuoteTimeout: 2 * time.Second, ReconnectTimeout: 5 * time.Second}
+	w.reset(start)
+	assert.Equal(t, 250*time.Millisecond, w.interval())
+
+	events, reconnect := w.check(start.Add(500*time.Millisecond), []string{"aapl"})
+	assert.Empty(t, events)
+	assert.False(t, reconnect)
+
+	w.quote("AAPL", start.Add(time.Second))
+	events, _ = w.check(start.Add(1500*time.Millisecond), []string{"aapl"})
+	require.Len(t, events, 1)
+	assert.Equal(t, StalenessHeartbeat, events[0].Kind)
+	events, _ = w.check(start.Add(2*time.Second), []string{"aapl"})
+	assert.Empty(t, events, "stale heartbeats are reported once")
+
+	events, _ = w.check(start.Add(3500*time.Millisecond), []string{"aapl"})
+	require.Len(t, events, 1)
+	assert.Equal(t, StalenessQuote, events[0].Kind)
+	assert.Equal(t, 2500*time.Millisecond, events[0].Elapsed)
+
+	w.heartbeat(start.Add(4 * time.Second))
+	events, reconnect = w.check(start.Add(4500*time.Millisecond), []string{"aapl"})
+	assert.Empty(t, events)
+	assert.False(t, reconnect)
+
+	events, reconnect = w.check(start.Add(9500*time.Millisecond), []string{"aapl"})
+	require.Len(t, events, 1)
+	assert.True(t, events[0].Reconnect)
+	assert.True(t, reconnect)
+
+	w.forget([]string{"aapl"})
+	assert.Empty(t, w.lastQuotes)
+	assert.Empty(t, w.staleSymbols)
+}
@@ -6,7 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -14,190 +18,687 @@ import (
 )
 
 const (
-	QuoteEndpoint = "wss://websockets.financialmodelingprep.com"
+	QuoteEndpoint  = "wss://websockets.financialmodelingprep.com"
+	CryptoEndpoint = "wss://crypto.financialmodelingprep.com"
+	ForexEndpoint  = "wss://forex.financialmodelingprep.com"
+
+	// acknowledgmentTimeout bounds the wait for the acknowledgment of the login and of the resubscription.
+	acknowledgmentTimeout = 10 * time.Second
+)
+
+var (
+	// ErrNotConnected is returned when subscribing while the client is not connected, e.g. while it reconnects,
+	// or when the connection drops before the subscription is acknowledged.
+	ErrNotConnected = errors.New("websocket not connected")
+
+	// ErrReconnectFailed is the error of the transition to ConnectionStateClosed once the reconnect policy gave up.
+	ErrReconnectFailed = errors.New("websocket reconnection failed")
 )
 
-// Connect connects to the FMP websocket endpoint.
+// websocketSession is a connection to an endpoint from Connect to Disconnect, across reconnections.
+type websocketSession struct {
+	endpoint string
+	feed     model.WebsocketFeed
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// websocketConnection is a connection with the requests waiting for their acknowledgment. FMP acknowledges the
+// requests of an event in order, so acknowledgments are matched to the waiting requests first in, first out.
+type websocketConnection struct {
+	*websocket.Conn
+	feed model.WebsocketFeed
+
+	lock     sync.Mutex
+	closed   bool
+	abortErr error
+	pending  map[model.WebsocketEventName][]chan error
+}
+
+func newWebsocketConnection(conn *websocket.Conn, feed model.WebsocketFeed) *websocketConnection {
+	return &websocketConnection{Conn: conn, feed: feed, pending: make(map[model.WebsocketEventName][]chan error)}
+}
+
+// expect registers a request of the event, which must be sent right after. The returned channel receives nil once
+// the request is acknowledged, or the error it failed with.
+func (c *websocketConnection) expect(event model.WebsocketEventName) (<-chan error, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return nil, ErrNotConnected
+	}
+	// The channel is buffered so that acknowledgments are delivered even if the request stopped waiting.
+	ack := make(chan error, 1)
+	c.pending[event] = append(c.pending[event], ack)
+	return ack, nil
+}
+
+// acknowledge delivers the acknowledgment to the oldest request of its event. It reports false if no request
+// waits for it.
+func (c *websocketConnection) acknowledge(msg model.WebsocketMesssage) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	pending := c.pending[msg.Event]
+	if len(pending) == 0 {
+		return false
+	}
+	c.pending[msg.Event] = pending[1:]
+	pending[0] <- acknowledgmentError(msg)
+	return true
+}
+
+// close closes the connection and fails the requests still waiting for their acknowledgment.
+func (c *websocketConnection) close() error {
+	c.lock.Lock()
+	c.closed = true
+	for event, pending := range c.pending {
+		for _, ack := range pending {
+			ack <- ErrNotConnected
+		}
+		delete(c.pending, event)
+	}
+	c.lock.Unlock()
+	return c.Close()
+}
+
+// abort closes the connection because of the error, which is then reported as the reason it dropped.
+func (c *websocketConnection) abort(err error) error {
+	c.lock.Lock()
+	c.abortErr = err
+	c.lock.Unlock()
+	return c.close()
+}
+
+// aborted returns the error the connection was aborted with, if any.
+func (c *websocketConnection) aborted() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.abortErr
+}
+
+// Connect connects to the FMP websocket endpoint, authenticates and subscribes to the symbols of the client, if any,
+// i.e. the ones subscribed to while polling with WithPollingFailover. Disconnect releases all the subscriptions, so
+// the client connects again without any after it.
 //
-// NOTE: It is the responsibility of the caller to call Disconnect to close the connection when done.
+// Once connected, the client reconnects according to its ReconnectPolicy whenever the connection drops,
+// authenticates again and resubscribes all the symbols it is subscribed to.
 //
-//nolint:gocognit
-func (wss *WebsocketClient) Connect(endpoint string) (err error) {
+// NOTE: It is the responsibility of the caller to call Disconnect to close the connection when done.
+func (wss *WebsocketClient) Connect(endpoint string) error {
 	wss.connectionLock.Lock()
-	defer wss.connectionLock.Unlock()
-
-	wss.connectOnce.Do(func() {
-		var conn *websocket.Conn
-		//nolint:bodyclose // The connection is closed in the Disconnect method.
-		conn, _, err = websocket.DefaultDialer.Dial(endpoint, nil)
-		if err != nil {
-			return
-		}
-		wss.connection = conn
-		wss.manager.Run(wss.maintainConnection)
+	if wss.session != nil {
+		wss.connectionLock.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(wss.ctx)
+	s := &websocketSession{endpoint: endpoint, feed: wss.feedOf(endpoint), cancel: cancel, done: make(chan struct{})}
+	wss.session = s
+	wss.connectionLock.Unlock()
 
-		msg := model.WebsocketAuthenticationRequest{
-			Event: model.WebsocketEventNameLogin,
-			Data:  model.WebsocketAuthenticationRequestData{APIKey: wss.config.APIKey},
-		}
-		if connErr := conn.WriteJSON(msg); connErr != nil {
-			err = fmt.Errorf("writing authentication message: %w", connErr)
-			return
-		}
-	L:
-		for {
-			select {
-			case <-wss.ctx.Done():
-				break L
-			case evt := <-wss.events:
-				if evt.Event != model.WebsocketEventNameLogin {
-					continue
-				}
-				if evt.Status == nil || *evt.Status >= 400 {
-					errMsg := fmt.Sprintf("unexpected error code: %d", evt.Status)
-					if evt.Message != nil {
-						errMsg = *evt.Message
-					}
-					err = fmt.Errorf("authentication failed: %s", errMsg)
-				}
-				break L
-			}
-		}
-	})
+	wss.setState(ConnectionStateConnecting, 0, nil)
+	conn, err := wss.connect(ctx, s)
 	if err != nil {
+		wss.connectionLock.Lock()
+		if wss.session == s {
+			wss.session = nil
+		}
+		wss.connectionLock.Unlock()
+		cancel()
+		close(s.done)
+		wss.setState(ConnectionStateClosed, 0, err)
 		return fmt.Errorf("dialing websocket connection: %w", err)
 	}
+	wss.metrics.recordConnection(wss.ctx, 1)
+	wss.setState(ConnectionStateAuthenticated, 0, nil)
+
+	wss.manager.Run(func(managerCtx context.Context) error {
+		defer close(s.done)
+		stop := context.AfterFunc(managerCtx, cancel)
+		defer stop()
+		wss.supervise(ctx, s, conn)
+		return nil
+	})
 	return nil
 }
 
+// Disconnect closes the connection, stops reconnecting and drops all subscriptions.
 func (wss *WebsocketClient) Disconnect() error {
 	wss.connectionLock.Lock()
-	defer wss.connectionLock.Unlock()
-	if wss.connection == nil {
+	s, conn := wss.session, wss.connection
+	wss.session = nil
+	wss.connection = nil
+	wss.connectionLock.Unlock()
+	if s == nil {
+		if wss.failover != nil {
+			wss.failover.Stop()
+		}
 		return nil
 	}
-	if err := wss.connection.Close(); err != nil {
-		return fmt.Errorf("closing websocket connection: %w", err)
+
+	s.cancel()
+	var err error
+	if conn != nil {
+		if closeErr := conn.close(); closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+			err = fmt.Errorf("closing websocket connection: %w", closeErr)
+		}
+	}
+	<-s.done
+	wss.book.Delete(wss.subscriptions.symbols()...)
+	wss.watchdog.forget(wss.subscriptions.symbols())
+	if wss.failover != nil {
+		wss.failover.forget(wss.subscriptions.symbols())
+	}
+	wss.subscriptions.reset()
+	wss.setState(ConnectionStateClosed, 0, nil)
+	return err
+}
+
+// Subscribe subscribes to the quotes of the symbols and waits for FMP to acknowledge it until the context is done.
+//
+// Subscriptions are reference counted per symbol: only the symbols that are not subscribed yet are sent to FMP,
+// in batches of the configured size, and each call must be matched by a call to Unsubscribe. A call either succeeds
+// or fails as a whole: if a batch fails, the batches acknowledged before are unsubscribed again and the symbols
+// that were already subscribed are left as they were. With WithPollingFailover, the symbols are polled instead
+// while the client is not connected.
+func (wss *WebsocketClient) Subscribe(ctx context.Context, symbols []string) error {
+	wss.subscribeQuotesLock.Lock()
+	missing, err := wss.subscribe(ctx, symbols)
+	wss.subscribeQuotesLock.Unlock()
+	if err != nil {
+		return err
 	}
-	wss.connection = nil
-	wss.connectOnce = sync.Once{}
 
+	// The quote book is seeded without the subscription lock, so that slow REST calls hold up neither the other
+	// subscriptions nor the resubscription of a new connection.
+	if wss.seedFrom != nil && len(missing) > 0 {
+		if err := wss.book.Seed(ctx, wss.seedFrom, missing, wss.seedOpts...); err != nil {
+			wss.logger.Warn("seeding quote book", slog.Any("error", err))
+		}
+		// The symbols unsubscribed while they were seeded are removed again.
+		_, released := wss.subscriptions.partition(missing)
+		wss.book.Delete(released...)
+	}
 	return nil
 }
 
-func (wss *WebsocketClient) Subscribe(symbols []string) error {
+// subscribe subscribes to the symbols and returns the ones that were not subscribed before. The caller must hold
+// the subscription lock.
+func (wss *WebsocketClient) subscribe(ctx context.Context, symbols []string) ([]string, error) {
+	live, missing := wss.subscriptions.partition(symbols)
+	var acquired []string
+	for batch := range slices.Chunk(missing, wss.batchSize()) {
+		if err := wss.request(ctx, model.WebsocketEventNameSubscribe, batch); err != nil && !wss.pollable(err) {
+			wss.rollback(ctx, acquired)
+			return nil, fmt.Errorf("subscription failed: %w", err)
+		}
+		wss.subscriptions.acquire(batch)
+		acquired = append(acquired, batch...)
+	}
+	// The symbols already subscribed are only referenced once all batches succeeded, as a failed call is not
+	// matched by a call to Unsubscribe.
+	wss.subscriptions.acquire(live)
+	return missing, nil
+}
+
+// Unsubscribe releases the subscriptions of the symbols and waits for FMP to acknowledge it until the context is done.
+//
+// Only the symbols whose last subscription is released are sent to FMP. Symbols that are not subscribed are ignored.
+// The symbols are released even if the client is not connected, in which case they are not resubscribed once it
+// reconnects.
+func (wss *WebsocketClient) Unsubscribe(ctx context.Context, symbols []string) error {
 	wss.subscribeQuotesLock.Lock()
 	defer wss.subscribeQuotesLock.Unlock()
+	return wss.unsubscribe(ctx, symbols)
+}
+
+// rollback unsubscribes the symbols a failed call to Subscribe acquired. It waits for the acknowledgment even if the
+// context of the call is done, for at most the acknowledgment timeout.
+func (wss *WebsocketClient) rollback(ctx context.Context, symbols []string) {
+	if len(symbols) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), acknowledgmentTimeout)
+	defer cancel()
+	if err := wss.unsubscribe(ctx, symbols); err != nil {
+		wss.logger.Warn("rolling back subscription", slog.Any("error", err))
+	}
+}
+
+// unsubscribe releases the subscriptions of the symbols and unsubscribes the released ones from FMP. The caller
+// must hold the subscription lock.
+func (wss *WebsocketClient) unsubscribe(ctx context.Context, symbols []string) error {
+	released := wss.subscriptions.release(symbols)
+	wss.book.Delete(released...)
+	wss.watchdog.forget(released)
+	if wss.failover != nil {
+		wss.failover.forget(released)
+	}
+	for batch := range slices.Chunk(released, wss.batchSize()) {
+		err := wss.request(ctx, model.WebsocketEventNameUnsubscribe, batch)
+		if errors.Is(err, ErrNotConnected) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unsubscription failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// request sends the subscribe or unsubscribe message of the symbols and waits for its acknowledgment.
+func (wss *WebsocketClient) request(ctx context.Context, event model.WebsocketEventName, symbols []string) error {
+	conn := wss.currentConnection()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	ack, err := conn.expect(event)
+	if err != nil {
+		return err
+	}
 	msg := model.WebsocketSubscriptionRequest{
-		Event: model.WebsocketEventNameSubscribe,
+		Event: event,
 		Data:  model.WebsocketSubscriptionRequestData{Symbols: symbols},
 	}
-	if err := wss.connection.WriteJSON(msg); err != nil {
-		return fmt.Errorf("writing subscription message: %w", err)
+	if err := conn.WriteJSON(msg); err != nil {
+		return fmt.Errorf("writing %s message: %w", event, err)
 	}
-L:
+	select {
+	case err := <-ack:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("awaiting %s acknowledgment: %w", event, ctx.Err())
+	}
+}
+
+func (wss *WebsocketClient) batchSize() int {
+	if wss.subscriptionBatchSize <= 0 {
+		return DefaultSubscriptionBatchSize
+	}
+	return wss.subscriptionBatchSize
+}
+
+// Quotes returns the quotes of all the subscribed symbols.
+//
+// The channel is a consumer of the client with the default buffer and BackpressureBlock: no quote is lost,
+// but the connection stalls while the buffer is full. Use Consume to receive the quotes with another policy.
+func (wss *WebsocketClient) Quotes() <-chan model.WebsocketQuote {
+	wss.quotesOnce.Do(func() {
+		wss.quotesConsumer = wss.broker.Consume()
+	})
+	return wss.quotesConsumer.Quotes()
+}
+
+// Consume adds a consumer of the quotes received from now on. The caller should call Close on the consumer once done.
+func (wss *WebsocketClient) Consume(opts ...ConsumerOption) *QuoteConsumer {
+	return wss.broker.Consume(opts...)
+}
+
+// DroppedQuotes returns the number of quotes dropped by the consumers of the client.
+func (wss *WebsocketClient) DroppedQuotes() uint64 {
+	return wss.broker.Dropped()
+}
+
+// supervise reads the connection until it drops and then reconnects, until the session is stopped or the reconnect
+// policy gives up.
+func (wss *WebsocketClient) supervise(ctx context.Context, s *websocketSession, conn *websocketConnection) {
 	for {
+		err := wss.maintainWatchedConnection(ctx, conn)
+		wss.releaseConnection(conn)
+		if ctx.Err() != nil {
+			return
+		}
+		wss.logger.Warn("websocket connection lost", slog.Any("error", err))
+		if conn = wss.reconnect(ctx, s, err); conn == nil {
+			return
+		}
+	}
+}
+
+// maintainWatchedConnection maintains the connection while the watchdog watches it, if enabled.
+func (wss *WebsocketClient) maintainWatchedConnection(ctx context.Context, conn *websocketConnection) error {
+	if !wss.watchdog.enabled() {
+		return wss.maintainConnection(ctx, conn)
+	}
+	wss.watchdog.reset(time.Now())
+	watchCtx, stop := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wss.watch(watchCtx, conn)
+	}()
+	err := wss.maintainConnection(ctx, conn)
+	stop()
+	wg.Wait()
+	if abortErr := conn.aborted(); abortErr != nil {
+		return abortErr
+	}
+	return err
+}
+
+// reconnect dials the endpoint of the session with backoff until it is authenticated and resubscribed. It returns
+// nil if the session is stopped or the reconnect policy gives up, in which case the client is closed.
+func (wss *WebsocketClient) reconnect(ctx context.Context, s *websocketSession, cause error) *websocketConnection {
+	wss.setState(ConnectionStateDegraded, 0, cause)
+	since := time.Now()
+	for attempt := 1; ; attempt++ {
+		wait, ok := wss.reconnectPolicy.backoff(attempt, time.Since(since))
+		if !ok {
+			wss.giveUp(s, attempt-1, fmt.Errorf("%w after %d attempts: %w", ErrReconnectFailed, attempt-1, cause))
+			return nil
+		}
 		select {
-		case <-wss.ctx.Done():
-			break L
-		case evt := <-wss.events:
-			if evt.Event != model.WebsocketEventNameSubscribe {
-				continue
-			}
-			if evt.Status == nil || *evt.Status >= 400 {
-				errMsg := fmt.Sprintf("unexpected error code: %d", evt.Status)
-				if evt.Message != nil {
-					errMsg = *evt.Message
-				}
-				return fmt.Errorf("subscription failed: %s", errMsg)
-			}
-			break L
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+
+		wss.setState(ConnectionStateConnecting, attempt, nil)
+		conn, err := wss.connect(ctx, s)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			cause = err
+			wss.logger.Warn("reconnecting websocket", slog.Int("attempt", attempt), slog.Any("error", err))
+			wss.setState(ConnectionStateDegraded, attempt, err)
+			continue
 		}
+		wss.metrics.recordConnection(wss.ctx, 1)
+		wss.setState(ConnectionStateAuthenticated, attempt, nil)
+		return conn
 	}
-	return nil
 }
 
-func (wss *WebsocketClient) Unsubscribe(symbols []string) error {
+// connect dials the endpoint of the session, authenticates, subscribes to all the symbols of the client and makes
+// the connection the current one.
+func (wss *WebsocketClient) connect(ctx context.Context, s *websocketSession) (*websocketConnection, error) {
+	conn, err := wss.dial(ctx, s.endpoint, s.feed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subscriptions wait for the connection, so that none of them is missed by it.
 	wss.subscribeQuotesLock.Lock()
 	defer wss.subscribeQuotesLock.Unlock()
-	msg := model.WebsocketSubscriptionRequest{
-		Event: model.WebsocketEventNameUnsubscribe,
-		Data:  model.WebsocketSubscriptionRequestData{Symbols: symbols},
+
+	for batch := range slices.Chunk(wss.subscriptions.symbols(), wss.batchSize()) {
+		msg := model.WebsocketSubscriptionRequest{
+			Event: model.WebsocketEventNameSubscribe,
+			Data:  model.WebsocketSubscriptionRequestData{Symbols: batch},
+		}
+		if err := conn.WriteJSON(msg); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("writing subscription message: %w", err)
+		}
+		if err := wss.awaitAcknowledgment(conn, s.feed, model.WebsocketEventNameSubscribe); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("resubscribing: %w", err)
+		}
 	}
-	if err := wss.connection.WriteJSON(msg); err != nil {
-		return fmt.Errorf("writing unsubscription message: %w", err)
+
+	c := newWebsocketConnection(conn, s.feed)
+	if !wss.acquireConnection(s, c) {
+		_ = conn.Close()
+		return nil, errors.New("disconnected while connecting")
 	}
-	return nil
+	return c, nil
 }
 
-func (wss *WebsocketClient) Quotes() <-chan model.WebsocketQuote {
-	return wss.quotes
+// dial opens a connection to the endpoint and authenticates.
+func (wss *WebsocketClient) dial(ctx context.Context, endpoint string, feed model.WebsocketFeed) (*websocket.Conn, error) {
+	//nolint:bodyclose // The connection is closed in the Disconnect method or once it dropped.
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	msg := model.WebsocketAuthenticationRequest{
+		Event: model.WebsocketEventNameLogin,
+		Data:  model.WebsocketAuthenticationRequestData{APIKey: wss.config.APIKey},
+	}
+	if err := conn.WriteJSON(msg); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("writing authentication message: %w", err)
+	}
+	if err := wss.awaitAcknowledgment(conn, feed, model.WebsocketEventNameLogin); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	return conn, nil
+}
+
+// awaitAcknowledgment reads the connection until the acknowledgment of the event. It must only be called before
+// the connection is maintained, as it reads the connection itself.
+func (wss *WebsocketClient) awaitAcknowledgment(conn *websocket.Conn, feed model.WebsocketFeed, event model.WebsocketEventName) error {
+	if err := conn.SetReadDeadline(time.Now().Add(acknowledgmentTimeout)); err != nil {
+		return err
+	}
+	for {
+		var rawMessage json.RawMessage
+		if err := conn.ReadJSON(&rawMessage); err != nil {
+			return fmt.Errorf("reading %s acknowledgment: %w", event, err)
+		}
+		wss.recorder.record(wss.logger, feed, rawMessage)
+		var msg model.WebsocketMesssage
+		if err := json.Unmarshal(rawMessage, &msg); err != nil {
+			return fmt.Errorf("unmarshaling %s acknowledgment: %w", event, err)
+		}
+		wss.metrics.recordMessage(wss.ctx, msg)
+		if msg.Event != event {
+			continue
+		}
+		if err := acknowledgmentError(msg); err != nil {
+			return err
+		}
+		return conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// acknowledgmentError returns the error of an acknowledgment, or nil if it succeeded.
+func acknowledgmentError(msg model.WebsocketMesssage) error {
+	if msg.Status != nil && *msg.Status < http.StatusBadRequest {
+		return nil
+	}
+	if msg.Message != nil {
+		return errors.New(*msg.Message)
+	}
+	if msg.Status == nil {
+		return errors.New("missing status")
+	}
+	return fmt.Errorf("unexpected error code: %d", *msg.Status)
 }
 
-//nolint:gocognit
-func (wss *WebsocketClient) maintainConnection(ctx context.Context) error {
-L:
+func (wss *WebsocketClient) currentConnection() *websocketConnection {
+	wss.connectionLock.Lock()
+	defer wss.connectionLock.Unlock()
+	return wss.connection
+}
+
+// acquireConnection makes the connection the current one, unless the session has been stopped.
+func (wss *WebsocketClient) acquireConnection(s *websocketSession, conn *websocketConnection) bool {
+	wss.connectionLock.Lock()
+	defer wss.connectionLock.Unlock()
+	if wss.session != s {
+		return false
+	}
+	wss.connection = conn
+	return true
+}
+
+// releaseConnection closes the connection and clears it if it is still the current one.
+func (wss *WebsocketClient) releaseConnection(conn *websocketConnection) {
+	wss.connectionLock.Lock()
+	if wss.connection == conn {
+		wss.connection = nil
+	}
+	wss.connectionLock.Unlock()
+	_ = conn.close()
+	wss.metrics.recordConnection(wss.ctx, -1)
+}
+
+// giveUp stops the session after the reconnect policy gave up.
+func (wss *WebsocketClient) giveUp(s *websocketSession, attempts int, err error) {
+	wss.connectionLock.Lock()
+	if wss.session == s {
+		wss.session = nil
+	}
+	wss.connectionLock.Unlock()
+	s.cancel()
+	wss.logger.Error("websocket closed", slog.Any("error", err))
+	wss.setState(ConnectionStateClosed, attempts, err)
+}
+
+func (wss *WebsocketClient) maintainConnection(ctx context.Context, conn *websocketConnection) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
 			var rawMessage json.RawMessage
-			if err := wss.connection.ReadJSON(&rawMessage); err != nil {
+			if err := conn.ReadJSON(&rawMessage); err != nil {
 				return fmt.Errorf("reading websocket message: %w", err)
 			}
-			msg := model.WebsocketMesssage{}
-			if err := json.Unmarshal(rawMessage, &msg); err != nil {
-				return fmt.Errorf("unmarshaling websocket message: %w", err)
+			wss.recorder.record(wss.logger, conn.feed, rawMessage)
+			if err := wss.processMessage(ctx, conn, conn.feed, rawMessage); err != nil {
+				return err
 			}
+		}
+	}
+}
 
-			switch msg.Event {
-			case model.WebsocketEventNameHeartbeat:
-				wss.logger.Debug("received heartbeat")
-				continue
-			case model.WebsocketEventNameLogin:
-				wss.events <- msg
-				wss.logger.Debug("authenticated", slog.Any("message", msg))
-				continue
-			case model.WebsocketEventNameSubscribe:
-				wss.events <- msg
-				wss.logger.Debug("subscribed", slog.Any("message", msg))
-				continue
-			case model.WebsocketEventNameUnsubscribe:
-				wss.events <- msg
-				wss.logger.Debug("unsubscribed", slog.Any("message", msg))
-				break L
-			default:
-				wss.logger.Debug("received message", slog.Any("raw", rawMessage))
-				if msg.Type == nil {
-					return errors.New("unknown message type: nil")
-				}
-				if err := wss.processRawMessage(*msg.Type, rawMessage); err != nil {
-					return fmt.Errorf("processing message: %w", err)
-				}
-			}
+// processMessage handles a message of the feed. Acknowledgments are only matched with the requests of the
+// connection, if any.
+func (wss *WebsocketClient) processMessage(ctx context.Context, conn *websocketConnection, feed model.WebsocketFeed, rawMessage json.RawMessage) error {
+	msg := model.WebsocketMesssage{}
+	if err := json.Unmarshal(rawMessage, &msg); err != nil {
+		return fmt.Errorf("unmarshaling websocket message: %w", err)
+	}
+	wss.metrics.recordMessage(ctx, msg)
+
+	switch msg.Event {
+	case model.WebsocketEventNameHeartbeat:
+		wss.logger.Debug("received heartbeat")
+		wss.watchdog.heartbeat(time.Now())
+	case model.WebsocketEventNameLogin, model.WebsocketEventNameSubscribe, model.WebsocketEventNameUnsubscribe:
+		if conn == nil || !conn.acknowledge(msg) {
+			wss.logger.Debug("received unexpected acknowledgment", slog.Any("message", msg))
+			return nil
+		}
+		wss.logger.Debug("received acknowledgment", slog.Any("message", msg))
+	default:
+		wss.logger.Debug("received message", slog.Any("raw", rawMessage))
+		if msg.Type == nil {
+			return errors.New("unknown message type: nil")
+		}
+		if err := wss.processRawMessage(ctx, feed, *msg.Type, rawMessage); err != nil {
+			return fmt.Errorf("processing message: %w", err)
 		}
 	}
 	return nil
 }
 
-func (wss *WebsocketClient) processRawMessage(typ model.WebsocketMessageType, msg json.RawMessage) error {
+func (wss *WebsocketClient) processRawMessage(
+	ctx context.Context,
+	feed model.WebsocketFeed,
+	typ model.WebsocketMessageType,
+	msg json.RawMessage,
+) error {
 	switch typ {
 	case model.WebsocketMessageTypeQuote:
-		if err := wss.processQuote(msg); err != nil {
+		if err := wss.processQuote(ctx, feed, msg); err != nil {
 			return fmt.Errorf("processing quote: %w", err)
 		}
+	case model.WebsocketMessageTypeTrade:
+		trade := model.WebsocketTrade{}
+		if err := json.Unmarshal(msg, &trade); err != nil {
+			return fmt.Errorf("unmarshaling websocket trade: %w", err)
+		}
+		wss.emit(ctx, model.WebsocketEvent{Feed: feed, Type: typ, Trade: &trade})
+	case model.WebsocketMessageTypeBreak:
+		b := model.WebsocketBreak{}
+		if err := json.Unmarshal(msg, &b); err != nil {
+			return fmt.Errorf("unmarshaling websocket break: %w", err)
+		}
+		wss.emit(ctx, model.WebsocketEvent{Feed: feed, Type: typ, Break: &b})
 	default:
 		wss.logger.Debug("received unknown message", slog.Any("message", msg))
 	}
 	return nil
 }
 
-func (wss *WebsocketClient) processQuote(msg json.RawMessage) error {
-	quote := model.WebsocketQuote{}
-	if err := json.Unmarshal(msg, &quote); err != nil {
-		return fmt.Errorf("unmarshaling websocket quote: %w", err)
+// processQuote decodes the quote according to the feed. Quotes of all feeds are published to the consumers as
+// model.WebsocketQuote, and as their own type to the events.
+func (wss *WebsocketClient) processQuote(ctx context.Context, feed model.WebsocketFeed, msg json.RawMessage) error {
+	event := model.WebsocketEvent{Feed: feed, Type: model.WebsocketMessageTypeQuote}
+	var quote model.WebsocketQuote
+	switch feed {
+	case model.WebsocketFeedCrypto:
+		event.CryptoQuote = &model.WebsocketCryptoQuote{}
+		if err := json.Unmarshal(msg, event.CryptoQuote); err != nil {
+			return fmt.Errorf("unmarshaling websocket crypto quote: %w", err)
+		}
+		quote = event.CryptoQuote.Quote()
+	case model.WebsocketFeedForex:
+		event.ForexQuote = &model.WebsocketForexQuote{}
+		if err := json.Unmarshal(msg, event.ForexQuote); err != nil {
+			return fmt.Errorf("unmarshaling websocket forex quote: %w", err)
+		}
+		quote = event.ForexQuote.Quote()
+	default:
+		if err := json.Unmarshal(msg, &quote); err != nil {
+			return fmt.Errorf("unmarshaling websocket quote: %w", err)
+		}
+		event.Quote = &quote
 	}
-	wss.quotes <- quote
+	wss.metrics.recordQuote(wss.ctx, quote)
+	wss.watchdog.quote(quote.Symbol, time.Now())
+	wss.book.Update(quote)
+	wss.broker.Publish(ctx, quote)
+	wss.emit(ctx, event)
 	return nil
 }
+
+// emit sends the event to the events channel, if it is enabled.
+func (wss *WebsocketClient) emit(ctx context.Context, event model.WebsocketEvent) {
+	events := wss.events.Load()
+	if events == nil {
+		return
+	}
+	select {
+	case *events <- event:
+	case <-ctx.Done():
+	}
+}
+
+// Events returns the quotes, trades and breaks of the feed as typed events, from the first call on.
+//
+// Like the one of Quotes, the channel is buffered and stalls the connection while its buffer is full.
+func (wss *WebsocketClient) Events() <-chan model.WebsocketEvent {
+	wss.eventsOnce.Do(func() {
+		events := make(chan model.WebsocketEvent, DefaultConsumerBuffer)
+		wss.events.Store(&events)
+	})
+	return *wss.events.Load()
+}
+
+// WithFeed sets the market of the endpoint, which decides how its quotes are decoded. Unless set, it is inferred
+// from the endpoint passed to Connect, CryptoEndpoint and ForexEndpoint being the crypto and forex feeds and any
+// other endpoint the stock feed.
+func WithFeed(feed model.WebsocketFeed) WebsocketOption {
+	return func(wss *WebsocketClient) {
+		wss.feed = feed
+	}
+}
+
+func (wss *WebsocketClient) feedOf(endpoint string) model.WebsocketFeed {
+	if wss.feed != "" {
+		return wss.feed
+	}
+	switch endpoint {
+	case CryptoEndpoint:
+		return model.WebsocketFeedCrypto
+	case ForexEndpoint:
+		return model.WebsocketFeedForex
+	}
+	return model.WebsocketFeedStock
+}
@@ -0,0 +1,327 @@
+package market
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+)
+
+func newWebsocketTestClient(t *testing.T, server *fmptest.Server, opts ...WebsocketOption) *WebsocketClient {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client, err := NewWebsocketClient(context.Background(), WebsocketClientConfig{APIKey: server.APIKey()}, logger, opts...)
+	require.NoError(t, err)
+	return client
+}
+
+// recordStates returns the option recording the state transitions of the client and the channel receiving them.
+func recordStates() (WebsocketOption, <-chan ConnectionStateChange) {
+	changes := make(chan ConnectionStateChange, 64)
+	return WithConnectionStateHandler(func(change ConnectionStateChange) {
+		changes <- change
+	}), changes
+}
+
+// awaitState waits for the transition to the state and returns it.
+func awaitState(t *testing.T, changes <-chan ConnectionStateChange, state ConnectionState) ConnectionStateChange {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case change := <-changes:
+			if change.To == state {
+				return change
+			}
+		case <-timeout:
+			t.Fatalf("no transition to %s", state)
+		}
+	}
+}
+
+func fastReconnectPolicy(maxAttempts int) ReconnectPolicy {
+	return ReconnectPolicy{MaxAttempts: maxAttempts, InitialInterval: 5 * time.Millisecond, MaxInterval: 20 * time.Millisecond, Multiplier: 2}
+}
+
+func TestWebsocketClient_Reconnect(t *testing.T) {
+	t.Run("success:resubscribe after drop", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		stateOpt, changes := recordStates()
+		client := newWebsocketTestClient(t, server, stateOpt, WithReconnectPolicy(fastReconnectPolicy(-1)))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		awaitState(t, changes, ConnectionStateAuthenticated)
+		require.NoError(t, client.Subscribe(context.Background(), []string{"AAPL", "MSFT"}))
+
+		server.DropWebsockets()
+		degraded := awaitState(t, changes, ConnectionStateDegraded)
+		assert.Error(t, degraded.Err)
+		reconnected := awaitState(t, changes, ConnectionStateAuthenticated)
+		assert.Equal(t, ConnectionStateConnecting, reconnected.From)
+		assert.Positive(t, reconnected.Attempt)
+		assert.Equal(t, ConnectionStateAuthenticated, client.State())
+		assert.Equal(t, 1, server.Subscribers("AAPL"))
+		assert.Equal(t, 1, server.Subscribers("MSFT"))
+
+		server.PublishQuote(model.WebsocketQuote{Symbol: "aapl", LastUpdated: time.Now().UnixMilli()})
+		select {
+		case got := <-client.Quotes():
+			assert.Equal(t, "aapl", got.Symbol)
+		case <-time.After(time.Second):
+			t.Fatal("no quote received after reconnecting")
+		}
+	})
+	t.Run("success:disconnect stops reconnecting", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		stateOpt, changes := recordStates()
+		client := newWebsocketTestClient(t, server, stateOpt, WithReconnectPolicy(fastReconnectPolicy(-1)))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		require.NoError(t, client.Disconnect())
+
+		closed := awaitState(t, changes, ConnectionStateClosed)
+		require.NoError(t, closed.Err)
+		assert.Equal(t, ConnectionStateClosed, client.State())
+		assert.ErrorIs(t, client.Subscribe(context.Background(), []string{"AAPL"}), ErrNotConnected)
+	})
+	t.Run("error:retries exhausted", func(t *testing.T) {
+		server := fmptest.NewServer()
+		stateOpt, changes := recordStates()
+		client := newWebsocketTestClient(t, server, stateOpt, WithReconnectPolicy(fastReconnectPolicy(2)))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		awaitState(t, changes, ConnectionStateAuthenticated)
+
+		server.Close()
+		closed := awaitState(t, changes, ConnectionStateClosed)
+		require.ErrorIs(t, closed.Err, ErrReconnectFailed)
+		assert.Equal(t, 2, closed.Attempt)
+		assert.ErrorContains(t, closed.Err, "after 2 attempts")
+	})
+	t.Run("error:reconnection disabled", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		stateOpt, changes := recordStates()
+		client := newWebsocketTestClient(t, server, stateOpt, WithReconnectPolicy(ReconnectPolicy{}))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+
+		server.DropWebsockets()
+		closed := awaitState(t, changes, ConnectionStateClosed)
+		require.ErrorIs(t, closed.Err, ErrReconnectFailed)
+		assert.Zero(t, closed.Attempt)
+	})
+}
+
+func TestReconnectPolicy_Backoff(t *testing.T) {
+	policy := ReconnectPolicy{MaxAttempts: 4, InitialInterval: 100 * time.Millisecond, MaxInterval: 250 * time.Millisecond, Multiplier: 2, MaxElapsedTime: time.Second}
+	tests := []struct {
+		name     string
+		attempt  int
+		elapsed  time.Duration
+		wantWait time.Duration
+		wantOK   bool
+	}{
+		{name: "success:first attempt", attempt: 1, wantWait: 100 * time.Millisecond, wantOK: true},
+		{name: "success:exponential", attempt: 2, wantWait: 200 * time.Millisecond, wantOK: true},
+		{name: "success:capped", attempt: 4, wantWait: 250 * time.Millisecond, wantOK: true},
+		{name: "error:max attempts", attempt: 5},
+		{name: "error:max elapsed time", attempt: 2, elapsed: 900 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := policy.backoff(tt.attempt, tt.elapsed)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantWait, wait)
+		})
+	}
+}
+
+func TestWebsocketClient_Subscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success:reference counting", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newWebsocketTestClient(t, server)
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+
+		require.NoError(t, client.Subscribe(ctx, []string{"AAPL"}))
+		require.NoError(t, client.Subscribe(ctx, []string{"aapl", "MSFT", "MSFT"}))
+		assert.Equal(t, []string{"aapl", "msft"}, client.Subscriptions())
+
+		require.NoError(t, client.Unsubscribe(ctx, []string{"AAPL", "MSFT"}))
+		assert.Equal(t, []string{"aapl"}, client.Subscriptions())
+		assert.Equal(t, 1, server.Subscribers("AAPL"))
+		assert.Zero(t, server.Subscribers("MSFT"))
+
+		require.NoError(t, client.Unsubscribe(ctx, []string{"AAPL"}))
+		require.NoError(t, client.Unsubscribe(ctx, []string{"AAPL", "NVDA"}), "unsubscribing twice is a no-op")
+		assert.Empty(t, client.Subscriptions())
+		assert.Zero(t, server.Subscribers("AAPL"))
+	})
+	t.Run("success:feed survives unsubscribe", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newWebsocketTestClient(t, server)
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+
+		require.NoError(t, client.Subscribe(ctx, []string{"AAPL", "MSFT"}))
+		require.NoError(t, client.Unsubscribe(ctx, []string{"AAPL"}))
+		server.PublishQuote(model.WebsocketQuote{Symbol: "msft", LastUpdated: time.Now().UnixMilli()})
+		select {
+		case got := <-client.Quotes():
+			assert.Equal(t, "msft", got.Symbol)
+		case <-time.After(time.Second):
+			t.Fatal("no quote received after unsubscribing")
+		}
+	})
+	t.Run("success:batches", func(t *testing.T) {
+		server := fmptest.NewServer(fmptest.WithMaxSubscriptionSymbols(2))
+		defer server.Close()
+		client := newWebsocketTestClient(t, server, WithSubscriptionBatchSize(2))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+
+		symbols := []string{"AAPL", "MSFT", "NVDA", "AMZN", "TSLA"}
+		require.NoError(t, client.Subscribe(ctx, symbols))
+		for _, symbol := range symbols {
+			assert.Equal(t, 1, server.Subscribers(symbol), symbol)
+		}
+		require.NoError(t, client.Unsubscribe(ctx, symbols))
+		for _, symbol := range symbols {
+			assert.Zero(t, server.Subscribers(symbol), symbol)
+		}
+	})
+	t.Run("error:rejected batch", func(t *testing.T) {
+		server := fmptest.NewServer(fmptest.WithMaxSubscriptionSymbols(2))
+		defer server.Close()
+		client := newWebsocketTestClient(t, server, WithSubscriptionBatchSize(3))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+
+		require.NoError(t, client.Subscribe(ctx, []string{"AAPL", "MSFT"}))
+		err := client.Subscribe(ctx, []string{"NVDA", "AMZN", "TSLA"})
+		assert.ErrorContains(t, err, "subscription failed: Too many tickers")
+		assert.Equal(t, []string{"aapl", "msft"}, client.Subscriptions())
+	})
+	t.Run("error:later batch rejected", func(t *testing.T) {
+		server := fmptest.NewServer(fmptest.WithMaxSubscriptions(3))
+		defer server.Close()
+		client := newWebsocketTestClient(t, server, WithSubscriptionBatchSize(2))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+
+		require.NoError(t, client.Subscribe(ctx, []string{"AAPL"}))
+		err := client.Subscribe(ctx, []string{"AAPL", "MSFT", "NVDA", "AMZN", "TSLA"})
+		assert.ErrorContains(t, err, "subscription failed: Subscription limit reached")
+		assert.Equal(t, []string{"aapl"}, client.Subscriptions(), "the acknowledged batches are rolled back")
+		assert.Zero(t, server.Subscribers("MSFT"))
+		assert.Zero(t, server.Subscribers("NVDA"))
+
+		// The failed call did not add a reference to AAPL, so a single call releases it.
+		require.NoError(t, client.Unsubscribe(ctx, []string{"AAPL"}))
+		assert.Empty(t, client.Subscriptions())
+		assert.Zero(t, server.Subscribers("AAPL"))
+
+		// Retrying subscribes the symbols once.
+		require.NoError(t, client.Subscribe(ctx, []string{"MSFT", "NVDA"}))
+		require.NoError(t, client.Unsubscribe(ctx, []string{"MSFT", "NVDA"}))
+		assert.Empty(t, client.Subscriptions())
+	})
+	t.Run("error:not connected", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newWebsocketTestClient(t, server)
+
+		assert.ErrorIs(t, client.Subscribe(ctx, []string{"AAPL"}), ErrNotConnected)
+		assert.Empty(t, client.Subscriptions())
+	})
+}
+
+func TestWebsocketClient_Events(t *testing.T) {
+	ctx := context.Background()
+
+	awaitEvent := func(t *testing.T, client *WebsocketClient) model.WebsocketEvent {
+		t.Helper()
+		select {
+		case event := <-client.Events():
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no event received")
+		}
+		return model.WebsocketEvent{}
+	}
+
+	t.Run("success:stock trades and breaks", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newWebsocketTestClient(t, server)
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		events := client.Events()
+		require.NoError(t, client.Subscribe(ctx, []string{"AAPL"}))
+
+		server.PublishQuote(testQuote("aapl", 1))
+		server.PublishTrade(model.WebsocketTrade{Symbol: "aapl", LastPrice: decimal.NewFromInt(2), LastSize: decimal.NewFromInt(100), LastUpdated: 1})
+		server.Broadcast(map[string]any{"type": "B", "s": "aapl", "t": 2})
+
+		quote := awaitEvent(t, client)
+		assert.Equal(t, model.WebsocketFeedStock, quote.Feed)
+		require.NotNil(t, quote.Quote)
+		assert.Equal(t, "aapl", quote.Symbol())
+		trade := awaitEvent(t, client)
+		assert.Equal(t, model.WebsocketMessageTypeTrade, trade.Type)
+		require.NotNil(t, trade.Trade)
+		assert.True(t, decimal.NewFromInt(100).Equal(trade.Trade.LastSize))
+		brk := awaitEvent(t, client)
+		require.NotNil(t, brk.Break)
+		assert.Equal(t, int64(2), brk.Break.LastUpdated)
+		assert.Empty(t, events)
+	})
+	t.Run("success:crypto quotes", func(t *testing.T) {
+		server := fmptest.NewServer()
+		defer server.Close()
+		client := newWebsocketTestClient(t, server, WithFeed(model.WebsocketFeedCrypto))
+		require.NoError(t, client.Connect(server.WebsocketURL()))
+		defer func() { assert.NoError(t, client.Disconnect()) }()
+		client.Events()
+		quotes := client.Quotes()
+		require.NoError(t, client.Subscribe(ctx, []string{"BTCUSD"}))
+
+		server.Broadcast(map[string]any{"type": "Q", "s": "btcusd", "e": "binance", "ap": "97000.5", "as": "0.2", "bp": "96999.5", "bs": "1.5", "t": 3})
+		event := awaitEvent(t, client)
+		assert.Equal(t, model.WebsocketFeedCrypto, event.Feed)
+		require.NotNil(t, event.CryptoQuote)
+		assert.Equal(t, "binance", event.CryptoQuote.Exchange)
+		select {
+		case quote := <-quotes:
+			assert.Equal(t, "btcusd", quote.Symbol)
+			assert.True(t, decimal.RequireFromString("96999.5").Equal(quote.BidPrice))
+		case <-time.After(time.Second):
+			t.Fatal("crypto quotes should be published as quotes")
+		}
+	})
+}
+
+func TestWebsocketClient_FeedOf(t *testing.T) {
+	client, err := NewWebsocketClient(context.Background(), WebsocketClientConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	assert.Equal(t, model.WebsocketFeedStock, client.feedOf(QuoteEndpoint))
+	assert.Equal(t, model.WebsocketFeedCrypto, client.feedOf(CryptoEndpoint))
+	assert.Equal(t, model.WebsocketFeedForex, client.feedOf(ForexEndpoint))
+
+	WithFeed(model.WebsocketFeedForex)(client)
+	assert.Equal(t, model.WebsocketFeedForex, client.feedOf("ws://localhost"))
+}
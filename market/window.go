@@ -0,0 +1,179 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.tradeforge.dev/fmp/model"
+	"go.tradeforge.dev/fmp/pkg/types"
+)
+
+// RangeFunc fetches the results of a date range, e.g. a method value such as client.GetHistoricalBars.
+type RangeFunc[P any, T any] func(ctx context.Context, params *P, opts ...model.RequestOption) ([]T, error)
+
+type windowing struct {
+	maxWindow   int
+	parallelism int
+	requestOpts []model.RequestOption
+}
+
+// RangeOption changes how FetchRange splits and fetches a date range.
+type RangeOption func(w *windowing)
+
+// WithMaxWindow overrides the maximum window of the endpoint in days.
+func WithMaxWindow(days int) RangeOption {
+	return func(w *windowing) {
+		w.maxWindow = days
+	}
+}
+
+// WithParallelism fetches up to n windows at the same time. Windows are fetched one after the other by default.
+func WithParallelism(n int) RangeOption {
+	return func(w *windowing) {
+		w.parallelism = n
+	}
+}
+
+// WithRangeRequestOptions applies the request options to every window request.
+func WithRangeRequestOptions(opts ...model.RequestOption) RangeOption {
+	return func(w *windowing) {
+		w.requestOpts = append(w.requestOpts, opts...)
+	}
+}
+
+// FetchRange fetches the date range of the params in windows no longer than the maximum window of the endpoint,
+// which FMP would otherwise silently truncate.
+//
+// The results of all windows are merged, sorted from the newest to the oldest like FMP does and de-duplicated.
+// Params without both dates are fetched in a single request. The first failed window cancels the others and its
+// error is returned.
+//
+//	bars, err := market.FetchRange(ctx, client.GetHistoricalBars, model.GetHistoricalBarsParams{
+//		Timeframe: model.Timeframe1Hour,
+//		Symbol:    "AAPL",
+//		Since:     "2024-01-01",
+//		Until:     "2024-12-31",
+//	})
+func FetchRange[P model.Windowed[P], T model.Timestamped](ctx context.Context, fetch RangeFunc[P, T], params P, opts ...RangeOption) ([]T, error) {
+	w := windowing{maxWindow: params.MaxWindow(), parallelism: 1}
+	for _, o := range opts {
+		o(&w)
+	}
+	if w.maxWindow < 1 {
+		return nil, fmt.Errorf("invalid max window: %d", w.maxWindow)
+	}
+
+	since, until := params.Window()
+	windows := []P{params}
+	if since != "" && until != "" {
+		split, err := splitWindow(since, until, w.maxWindow)
+		if err != nil {
+			return nil, err
+		}
+		windows = make([]P, 0, len(split))
+		for _, window := range split {
+			windows = append(windows, params.WithWindow(window[0], window[1]))
+		}
+	}
+
+	results, err := fetchWindows(ctx, fetch, windows, w)
+	if err != nil {
+		return nil, err
+	}
+	return mergeWindows(results)
+}
+
+// splitWindow splits the inclusive date range into consecutive windows of at most days days.
+func splitWindow(since, until types.Date, days int) ([][2]types.Date, error) {
+	from, err := time.Parse(time.DateOnly, string(since))
+	if err != nil {
+		return nil, fmt.Errorf("parsing since date: %w", err)
+	}
+	to, err := time.Parse(time.DateOnly, string(until))
+	if err != nil {
+		return nil, fmt.Errorf("parsing until date: %w", err)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("until date %s is before since date %s", until, since)
+	}
+
+	var windows [][2]types.Date
+	for start := from; !start.After(to); start = start.AddDate(0, 0, days) {
+		end := start.AddDate(0, 0, days-1)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, [2]types.Date{types.DateFromTime(start), types.DateFromTime(end)})
+	}
+	return windows, nil
+}
+
+func fetchWindows[P model.Windowed[P], T any](ctx context.Context, fetch RangeFunc[P, T], windows []P, w windowing) ([][]T, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([][]T, len(windows))
+	sem := make(chan struct{}, max(w.parallelism, 1))
+	var wg sync.WaitGroup
+	for i := range windows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			res, err := fetch(ctx, &windows[i], w.requestOpts...)
+			if err != nil {
+				since, until := windows[i].Window()
+				cancel(fmt.Errorf("fetching window %s to %s: %w", since, until, err))
+				return
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// mergeWindows returns the results of all windows from the newest to the oldest without duplicates. It fails if
+// the time of a result cannot be parsed.
+func mergeWindows[T model.Timestamped](results [][]T) ([]T, error) {
+	type timestamped struct {
+		item T
+		time time.Time
+	}
+	seen := make(map[string]struct{})
+	var sorted []timestamped
+	for _, res := range results {
+		for _, item := range res {
+			if _, ok := seen[item.Key()]; ok {
+				continue
+			}
+			seen[item.Key()] = struct{}{}
+			t, err := item.Timestamp()
+			if err != nil {
+				return nil, fmt.Errorf("parsing time of %q: %w", item.Key(), err)
+			}
+			sorted = append(sorted, timestamped{item: item, time: t})
+		}
+	}
+	slices.SortStableFunc(sorted, func(a, b timestamped) int {
+		return b.time.Compare(a.time)
+	})
+	merged := make([]T, 0, len(sorted))
+	for _, s := range sorted {
+		merged = append(merged, s.item)
+	}
+	return merged, nil
+}
@@ -0,0 +1,112 @@
+package market
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/fmptest"
+	"go.tradeforge.dev/fmp/model"
+	"go.tradeforge.dev/fmp/pkg/types"
+)
+
+func TestFetchRange(t *testing.T) {
+	ctx := context.Background()
+	barsPath := strings.Replace(GetHistoricalBarsPath, ":timeframe", string(model.Timeframe1Hour), 1)
+	params := model.GetHistoricalBarsParams{Timeframe: model.Timeframe1Hour, Symbol: "AAPL", Since: "2025-01-01", Until: "2025-03-31"}
+
+	server := fmptest.NewServer()
+	defer server.Close()
+	client := newFixtureTestClient(server)
+	want, err := client.GetHistoricalBars(ctx, &params)
+	require.NoError(t, err)
+	require.NotEmpty(t, want)
+
+	tests := []struct {
+		name     string
+		opts     []RangeOption
+		wantHits int
+	}{
+		{name: "success:single window", wantHits: 1},
+		{name: "success:sequential windows", opts: []RangeOption{WithMaxWindow(30)}, wantHits: 3},
+		{name: "success:parallel windows", opts: []RangeOption{WithMaxWindow(7), WithParallelism(4)}, wantHits: 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.Reset()
+			got, err := FetchRange(ctx, client.GetHistoricalBars, params, tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+			assert.Equal(t, tt.wantHits, server.Hits(barsPath))
+		})
+	}
+
+	t.Run("success:calendar", func(t *testing.T) {
+		server.Reset()
+		since, until := types.Date("2025-01-01"), types.Date("2025-02-28")
+		got, err := FetchRange(ctx, client.GetDividendsCalendar, model.GetDividendsCalendarParams{Since: &since, Until: &until}, WithMaxWindow(14))
+		require.NoError(t, err)
+		assert.Len(t, got, 8)
+		assert.Equal(t, 5, server.Hits(GetDividendsCalendarPath))
+		for i := 1; i < len(got); i++ {
+			assert.False(t, got[i].Date.Time().After(got[i-1].Date.Time()), "results should be sorted from the newest")
+		}
+	})
+	t.Run("error:failed window", func(t *testing.T) {
+		server.Reset()
+		server.Fail(barsPath, fmptest.Fault{StatusCode: http.StatusPaymentRequired, Message: fmptest.MessageRestrictedEndpoint})
+
+		_, err := FetchRange(ctx, client.GetHistoricalBars, params, WithMaxWindow(30), WithParallelism(2))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "fetching window")
+	})
+	t.Run("error:invalid window", func(t *testing.T) {
+		invalid := params
+		invalid.Since, invalid.Until = invalid.Until, invalid.Since
+		_, err := FetchRange(ctx, client.GetHistoricalBars, invalid)
+		assert.ErrorContains(t, err, "is before since date")
+	})
+}
+
+func TestSplitWindow(t *testing.T) {
+	tests := []struct {
+		name  string
+		since types.Date
+		until types.Date
+		days  int
+		want  [][2]types.Date
+	}{
+		{name: "success:single day", since: "2025-01-01", until: "2025-01-01", days: 90, want: [][2]types.Date{{"2025-01-01", "2025-01-01"}}},
+		{name: "success:exact windows", since: "2025-01-01", until: "2025-01-20", days: 10, want: [][2]types.Date{{"2025-01-01", "2025-01-10"}, {"2025-01-11", "2025-01-20"}}},
+		{name: "success:short last window", since: "2024-12-30", until: "2025-01-02", days: 3, want: [][2]types.Date{{"2024-12-30", "2025-01-01"}, {"2025-01-02", "2025-01-02"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitWindow(tt.since, tt.until, tt.days)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMergeWindows(t *testing.T) {
+	got, err := mergeWindows([][]model.HistoricalPriceEOD{
+		{{Symbol: "AAPL", Date: "2025-01-03"}, {Symbol: "AAPL", Date: "2025-01-02"}},
+		{{Symbol: "AAPL", Date: "2025-01-06"}, {Symbol: "AAPL", Date: "2025-01-03"}, {Symbol: "MSFT", Date: "2025-01-03"}},
+	})
+	require.NoError(t, err)
+	keys := make([]string, 0, len(got))
+	for _, p := range got {
+		keys = append(keys, p.Key())
+	}
+	assert.Equal(t, []string{"AAPL@2025-01-06", "AAPL@2025-01-03", "MSFT@2025-01-03", "AAPL@2025-01-02"}, keys)
+
+	t.Run("error:missing date", func(t *testing.T) {
+		_, err := mergeWindows([][]model.HistoricalPriceEOD{{{Symbol: "AAPL", Date: "2025-01-02"}, {Symbol: "AAPL"}}})
+		assert.ErrorContains(t, err, `parsing time of "AAPL@"`)
+	})
+}
@@ -76,7 +76,13 @@ type OriginalGetExchangeHolidaysResponse struct {
 type mappedHolidays map[string]any
 
 func (h mappedHolidays) Year() int {
-	return h["year"].(int)
+	switch year := h["year"].(type) {
+	case int:
+		return year
+	case float64: // JSON numbers are decoded as float64
+		return int(year)
+	}
+	return 0
 }
 
 func (h mappedHolidays) Get() []Holiday {
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.tradeforge.dev/fmp/pkg/types"
+)
+
+func TestOriginalGetExchangeHolidaysResponse_UnmarshalJSON(t *testing.T) {
+	raw := `{"stockMarketHolidays": [{"year": 2024, "2024-01-01": "New Years Day"}, {"year": 2025, "2025-12-25": "Christmas"}]}`
+
+	var response OriginalGetExchangeHolidaysResponse
+	require.NoError(t, json.Unmarshal([]byte(raw), &response))
+	require.Len(t, response.HolidaysByYear, 2)
+
+	// JSON numbers are decoded as float64 into the map.
+	holidays := response.HolidaysByYear[1]
+	assert.Equal(t, 2025, holidays.Year())
+	assert.Equal(t, []Holiday{{Date: types.Date("2025-12-25"), Name: "Christmas"}}, holidays.Get())
+
+	assert.Equal(t, 2024, mappedHolidays{"year": 2024}.Year())
+	assert.Zero(t, mappedHolidays{}.Year())
+}
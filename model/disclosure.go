@@ -14,14 +14,26 @@ type GetHouseFinancialDisclosuresParams struct {
 	Page  *uint `query:"page"  validate:"omitempty,min=0,max=100"`
 }
 
-type GetHouseFinancialDisclosuresResponse []FinancialDisclosure
+func (p GetHouseFinancialDisclosuresParams) WithPage(page, size uint) GetHouseFinancialDisclosuresParams {
+	limit := int(size) //nolint:gosec // page sizes are small
+	p.Page, p.Limit = &page, &limit
+	return p
+}
+
+type GetHouseFinancialDisclosuresResponse = []FinancialDisclosure
 
 type GetSenateFinancialDisclosuresParams struct {
 	Limit *int  `query:"limit" validate:"omitempty,min=1,max=250"`
 	Page  *uint `query:"page"  validate:"omitempty,min=0,max=100"`
 }
 
-type GetSenateFinancialDisclosuresResponse []FinancialDisclosure
+func (p GetSenateFinancialDisclosuresParams) WithPage(page, size uint) GetSenateFinancialDisclosuresParams {
+	limit := int(size) //nolint:gosec // page sizes are small
+	p.Page, p.Limit = &page, &limit
+	return p
+}
+
+type GetSenateFinancialDisclosuresResponse = []FinancialDisclosure
 
 type FinancialDisclosure struct {
 	Symbol           string                         `json:"symbol"`
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
 
 	"go.tradeforge.dev/fmp/pkg/types"
@@ -11,6 +13,26 @@ type GetDividendsCalendarParams struct {
 	Until *types.Date `query:"to"`
 }
 
+func (p GetDividendsCalendarParams) Window() (since, until types.Date) {
+	if p.Since != nil {
+		since = *p.Since
+	}
+	if p.Until != nil {
+		until = *p.Until
+	}
+	return since, until
+}
+
+func (p GetDividendsCalendarParams) WithWindow(since, until types.Date) GetDividendsCalendarParams {
+	p.Since, p.Until = &since, &until
+	return p
+}
+
+// MaxWindow returns the window of the dividends calendar, which FMP caps at about three months.
+func (p GetDividendsCalendarParams) MaxWindow() int {
+	return calendarMaxWindow
+}
+
 type GetDividendsCalendarResponse struct {
 	Symbol          string                    `json:"symbol"`
 	Date            types.Date                `json:"date"`
@@ -22,3 +44,11 @@ type GetDividendsCalendarResponse struct {
 	Yield           decimal.Decimal           `json:"yield"`
 	Frequency       string                    `json:"frequency"`
 }
+
+func (r GetDividendsCalendarResponse) Timestamp() (time.Time, error) {
+	return time.Parse(time.DateOnly, string(r.Date))
+}
+
+func (r GetDividendsCalendarResponse) Key() string {
+	return r.Symbol + "@" + string(r.Date)
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
 
 	"go.tradeforge.dev/fmp/pkg/types"
@@ -11,6 +13,26 @@ type GetEarningsCalendarParams struct {
 	Until *types.Date `query:"to"`
 }
 
+func (p GetEarningsCalendarParams) Window() (since, until types.Date) {
+	if p.Since != nil {
+		since = *p.Since
+	}
+	if p.Until != nil {
+		until = *p.Until
+	}
+	return since, until
+}
+
+func (p GetEarningsCalendarParams) WithWindow(since, until types.Date) GetEarningsCalendarParams {
+	p.Since, p.Until = &since, &until
+	return p
+}
+
+// MaxWindow returns the window of the earnings calendar, which FMP caps at about three months.
+func (p GetEarningsCalendarParams) MaxWindow() int {
+	return calendarMaxWindow
+}
+
 type GetEarningsCalendarResponse struct {
 	Date             types.Date       `json:"date"`
 	Symbol           string           `json:"symbol"`
@@ -20,3 +42,11 @@ type GetEarningsCalendarResponse struct {
 	RevenueEstimated *decimal.Decimal `json:"revenueEstimated"`
 	LastUpdatedAt    types.Date       `json:"lastUpdated"`
 }
+
+func (r GetEarningsCalendarResponse) Timestamp() (time.Time, error) {
+	return time.Parse(time.DateOnly, string(r.Date))
+}
+
+func (r GetEarningsCalendarResponse) Key() string {
+	return r.Symbol + "@" + string(r.Date)
+}
@@ -9,7 +9,12 @@ type GetFMPArticlesParams struct {
 	Limit *uint `query:"limit"`
 }
 
-type GetFMPArticlesResponse []FMPArticle
+func (p GetFMPArticlesParams) WithPage(page, size uint) GetFMPArticlesParams {
+	p.Page, p.Limit = &page, &size
+	return p
+}
+
+type GetFMPArticlesResponse = []FMPArticle
 
 type FMPArticle struct {
 	Ticker  string         `json:"ticker"`
@@ -29,6 +34,11 @@ type GetLatestNewsParams struct {
 	Limit *uint       `query:"limit"`
 }
 
+func (p GetLatestNewsParams) WithPage(page, size uint) GetLatestNewsParams {
+	p.Page, p.Limit = &page, &size
+	return p
+}
+
 type GetNewsParams struct {
 	Symbols string      `query:"symbols"`
 	Since   *types.Date `query:"from"`
@@ -37,7 +47,12 @@ type GetNewsParams struct {
 	Limit   *uint       `query:"limit"`
 }
 
-type GetNewsResponse []NewsArticle
+func (p GetNewsParams) WithPage(page, size uint) GetNewsParams {
+	p.Page, p.Limit = &page, &size
+	return p
+}
+
+type GetNewsResponse = []NewsArticle
 
 type NewsArticle struct {
 	Symbol        string         `json:"symbol"`
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/shopspring/decimal"
 
 	"go.tradeforge.dev/fmp/pkg/types"
@@ -13,6 +15,20 @@ type GetHistoricalBarsParams struct {
 	Until     types.Date `query:"to,omitempty"`
 }
 
+func (p GetHistoricalBarsParams) Window() (since, until types.Date) {
+	return p.Since, p.Until
+}
+
+func (p GetHistoricalBarsParams) WithWindow(since, until types.Date) GetHistoricalBarsParams {
+	p.Since, p.Until = since, until
+	return p
+}
+
+// MaxWindow returns the window of intraday charts, which FMP caps at about three months.
+func (p GetHistoricalBarsParams) MaxWindow() int {
+	return intradayMaxWindow
+}
+
 type GetHistoricalBarsResponse = []Bar
 
 type Bar struct {
@@ -24,12 +40,34 @@ type Bar struct {
 	DateTime types.DateTime  `json:"date"`
 }
 
+func (b Bar) Timestamp() (time.Time, error) {
+	return time.Parse(time.DateTime, string(b.DateTime))
+}
+
+func (b Bar) Key() string {
+	return string(b.DateTime)
+}
+
 type GetHistoricalPricesEODParams struct {
 	Symbol string     `query:"symbol,required"`
 	Since  types.Date `query:"from,omitempty"`
 	Until  types.Date `query:"to,omitempty"`
 }
 
+func (p GetHistoricalPricesEODParams) Window() (since, until types.Date) {
+	return p.Since, p.Until
+}
+
+func (p GetHistoricalPricesEODParams) WithWindow(since, until types.Date) GetHistoricalPricesEODParams {
+	p.Since, p.Until = since, until
+	return p
+}
+
+// MaxWindow returns the window of end of day prices, which FMP caps at five years.
+func (p GetHistoricalPricesEODParams) MaxWindow() int {
+	return dailyMaxWindow
+}
+
 type GetHistoricalPricesEODResponse = []HistoricalPriceEOD
 
 type HistoricalPriceEOD struct {
@@ -45,6 +83,14 @@ type HistoricalPriceEOD struct {
 	VWAP          decimal.Decimal `json:"vwap"`
 }
 
+func (p HistoricalPriceEOD) Timestamp() (time.Time, error) {
+	return time.Parse(time.DateOnly, string(p.Date))
+}
+
+func (p HistoricalPriceEOD) Key() string {
+	return p.Symbol + "@" + string(p.Date)
+}
+
 type GetQuoteParams struct {
 	Symbol string `query:"symbol,required"`
 }
@@ -84,6 +130,12 @@ type TickerQuote struct {
 	Timestamp        int64           `json:"timestamp"`
 }
 
+// WebsocketQuote returns the quote in the shape of the websocket feed. It only has a last price, as the REST
+// endpoints do not return the bid and the ask.
+func (q TickerQuote) WebsocketQuote() WebsocketQuote {
+	return WebsocketQuote{Symbol: q.Symbol, LastPrice: q.Price, LastUpdated: q.Timestamp}
+}
+
 type TickerShortQuote struct {
 	Symbol string          `json:"symbol"`
 	Price  decimal.Decimal `json:"price"`
@@ -116,7 +168,21 @@ type GetHistoricalMarketCapParams struct {
 	Until  types.Date `query:"to,omitempty"`
 }
 
-type GetHistoricalMarketCapResponse []HistoricalMarketCap
+func (p GetHistoricalMarketCapParams) Window() (since, until types.Date) {
+	return p.Since, p.Until
+}
+
+func (p GetHistoricalMarketCapParams) WithWindow(since, until types.Date) GetHistoricalMarketCapParams {
+	p.Since, p.Until = since, until
+	return p
+}
+
+// MaxWindow returns the window of the market capitalization history, which FMP caps at five years.
+func (p GetHistoricalMarketCapParams) MaxWindow() int {
+	return dailyMaxWindow
+}
+
+type GetHistoricalMarketCapResponse = []HistoricalMarketCap
 
 type HistoricalMarketCap struct {
 	Symbol string          `json:"symbol"`
@@ -124,6 +190,14 @@ type HistoricalMarketCap struct {
 	Value  decimal.Decimal `json:"marketCap"`
 }
 
+func (c HistoricalMarketCap) Timestamp() (time.Time, error) {
+	return time.Parse(time.DateOnly, string(c.Date))
+}
+
+func (c HistoricalMarketCap) Key() string {
+	return c.Symbol + "@" + string(c.Date)
+}
+
 type GetBulkPriceEODParams struct {
 	Date types.Date `query:"date,required"`
 }
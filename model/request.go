@@ -3,6 +3,7 @@ package model
 import (
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // RequestOptions are used to configure client calls.
@@ -24,8 +25,30 @@ type RequestOptions struct {
 
 	// Trace enables request tracing.
 	Trace bool
+
+	// CacheControl defines how the request interacts with the response cache of the client.
+	CacheControl CacheControl
+
+	// Timeout limits the duration of the call, including retries. Zero means no limit besides the client timeout.
+	Timeout time.Duration
+
+	// Stream leaves the body of successful responses unread, so that it can be consumed as it arrives.
+	// It is set by the client for calls that stream their response.
+	Stream bool
 }
 
+// CacheControl defines how a request interacts with the response cache.
+type CacheControl int
+
+const (
+	// CacheControlDefault serves the response from the cache if present and caches it otherwise.
+	CacheControlDefault CacheControl = iota
+	// CacheControlBypass neither reads from nor writes to the cache.
+	CacheControlBypass
+	// CacheControlRefresh always calls the API and replaces the cached response.
+	CacheControlRefresh
+)
+
 // RequestOption changes the configuration of RequestOptions.
 type RequestOption func(o *RequestOptions)
 
@@ -76,3 +99,24 @@ func WithTrace(trace bool) RequestOption {
 		o.Trace = trace
 	}
 }
+
+// WithCacheBypass skips the response cache for the request.
+func WithCacheBypass() RequestOption {
+	return func(o *RequestOptions) {
+		o.CacheControl = CacheControlBypass
+	}
+}
+
+// WithCacheRefresh forces the request to the API and refreshes the cached response.
+func WithCacheRefresh() RequestOption {
+	return func(o *RequestOptions) {
+		o.CacheControl = CacheControlRefresh
+	}
+}
+
+// WithTimeout limits the duration of the call, including retries, without changing the client timeout.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *RequestOptions) {
+		o.Timeout = timeout
+	}
+}
@@ -1,6 +1,10 @@
 package model
 
-import "fmt"
+import (
+	"time"
+
+	"go.tradeforge.dev/fmp/pkg/types"
+)
 
 // BaseResponse has all possible attributes that any response can use. It's intended to be embedded in a domain specific
 // response struct.
@@ -31,19 +35,67 @@ func (p PaginationHooks) NextPage() string {
 	return p.NextURL
 }
 
-// ResponseError represents an API response with an error status code.
-type ResponseError struct {
-	BaseResponse
+// Pageable is implemented by the params of endpoints that return their results page by page.
+type Pageable[P any] interface {
+	// WithPage returns a copy of the params that requests the page of the given size. Pages start at 0.
+	WithPage(page, size uint) P
+}
+
+// Maximum windows of the endpoints in days.
+const (
+	intradayMaxWindow = 90
+	dailyMaxWindow    = 5 * 365
+	calendarMaxWindow = 90
+)
+
+// Windowed is implemented by the params of endpoints that return the results of a date range. FMP truncates the
+// results of ranges longer than the maximum window of the endpoint without reporting an error.
+type Windowed[P any] interface {
+	// Window returns the date range requested by the params. Either date is empty if it is not set.
+	Window() (since, until types.Date)
 
-	// An error message for unsuccessful requests.
-	ErrorMessage string `json:"error,omitempty"`
+	// WithWindow returns a copy of the params that requests the date range.
+	WithWindow(since, until types.Date) P
 
-	// An HTTP status code for unsuccessful requests.
-	StatusCode         int
-	OriginalStatusCode int
+	// MaxWindow returns the number of days of the longest date range the endpoint returns in full.
+	MaxWindow() int
 }
 
-// Error returns the details of an error response.
-func (e *ResponseError) Error() string {
-	return fmt.Sprintf("bad status code: %d", e.StatusCode)
+// Timestamped is implemented by the results of endpoints that return the results of a date range.
+type Timestamped interface {
+	// Timestamp returns the time the result refers to, or an error if FMP sent a missing or malformed date.
+	Timestamp() (time.Time, error)
+
+	// Key identifies the result among the results of overlapping date ranges.
+	Key() string
+}
+
+// PartialResult is the result of a lenient bulk call: the rows that were decoded and the ones that were skipped.
+type PartialResult[T any] struct {
+	Rows    []T
+	Skipped []SkippedRow
+}
+
+// SkippedRow is a row of a bulk response that could not be decoded.
+type SkippedRow struct {
+	// Row is the number of the row, starting at 1 for the first row after the header.
+	Row int
+
+	// Line is the line of the row in the response, starting at 1 for the header.
+	Line int
+
+	// Record is the raw row. It is empty if the row could not be read as CSV.
+	Record []string
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+// SkipPolicy defines how many malformed rows a lenient bulk call skips before it fails.
+type SkipPolicy struct {
+	// MaxSkippedRows fails the call once more rows are skipped. Zero means no limit.
+	MaxSkippedRows int
+
+	// MaxSkippedRatio fails the call if the ratio of skipped rows among all rows exceeds it. Zero means no limit.
+	MaxSkippedRatio float64
 }
@@ -10,11 +10,17 @@ import (
 )
 
 type GetInsiderTradesParams struct {
-	Date *types.Date `query:"date,omitempty"`
-	Page *uint       `query:"page,omitempty"`
+	Date  *types.Date `query:"date,omitempty"`
+	Page  *uint       `query:"page,omitempty"`
+	Limit *uint       `query:"limit,omitempty"`
+}
+
+func (p GetInsiderTradesParams) WithPage(page, size uint) GetInsiderTradesParams {
+	p.Page, p.Limit = &page, &size
+	return p
 }
 
-type GetInsiderTradesResponse []InsiderTrade
+type GetInsiderTradesResponse = []InsiderTrade
 
 type InsiderTrade struct {
 	Symbol                   string          `json:"symbol"`
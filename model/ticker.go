@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
 
@@ -39,6 +40,23 @@ func (i *IgnoreUnmarshalFailure[T]) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+func (i *IgnoreUnmarshalFailure[T]) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	i.value = new(T)
+	var err error
+	if u, ok := any(i.value).(encoding.TextUnmarshaler); ok {
+		err = u.UnmarshalText(b)
+	} else {
+		err = json.Unmarshal(b, i.value)
+	}
+	if err != nil {
+		i.value = nil
+	}
+	return nil
+}
+
 type GetCompanyProfileResponse struct {
 	Symbol            string           `json:"symbol"`
 	Exchange          string           `json:"exchange"`
@@ -119,6 +137,8 @@ type BulkCompanyProfileResponse struct {
 	IsFund            types.Bool                                                                             `json:"isFund"`
 }
 
+// ParseCompanyProfileCSVRecord parses a record of the bulk company profiles through a JSON round trip.
+// encoder.CSVDecoder decodes records directly into the struct and is several times faster.
 func ParseCompanyProfileCSVRecord(header []string, record []string) (*BulkCompanyProfileResponse, error) {
 	resultMap := make(map[string]any)
 	for j, field := range record {
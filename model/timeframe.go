@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Timeframe string
 
 const (
@@ -10,3 +12,23 @@ const (
 	Timeframe1Hour Timeframe = "1hour"
 	Timeframe4Hour Timeframe = "4hour"
 )
+
+// Duration returns the length of the bars of the timeframe. It is zero for unknown timeframes.
+func (t Timeframe) Duration() time.Duration {
+	switch t {
+	case Timeframe1Min:
+		return time.Minute
+	case Timeframe5Min:
+		return 5 * time.Minute //nolint:mnd // timeframe length
+	case Timeframe15Min:
+		return 15 * time.Minute //nolint:mnd // timeframe length
+	case Timeframe30Min:
+		return 30 * time.Minute //nolint:mnd // timeframe length
+	case Timeframe1Hour:
+		return time.Hour
+	case Timeframe4Hour:
+		return 4 * time.Hour //nolint:mnd // timeframe length
+	default:
+		return 0
+	}
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"log/slog"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -20,6 +21,17 @@ type WebsocketMessageType string
 
 const (
 	WebsocketMessageTypeQuote WebsocketMessageType = "Q"
+	WebsocketMessageTypeTrade WebsocketMessageType = "T"
+	WebsocketMessageTypeBreak WebsocketMessageType = "B"
+)
+
+// WebsocketFeed is the market served by a websocket endpoint. It decides the shape of the quotes of the endpoint.
+type WebsocketFeed string
+
+const (
+	WebsocketFeedStock  WebsocketFeed = "stock"
+	WebsocketFeedCrypto WebsocketFeed = "crypto"
+	WebsocketFeedForex  WebsocketFeed = "forex"
 )
 
 type WebsocketMesssage struct {
@@ -74,9 +86,168 @@ type WebsocketQuote struct {
 	BidPrice    decimal.Decimal `json:"bp"`
 	BidSize     decimal.Decimal `json:"bs"`
 	LastPrice   decimal.Decimal `json:"lp"`
+	LastSize    decimal.Decimal `json:"ls"`
 	LastUpdated int64           `json:"t"`
 }
 
+// Time returns the time of the last update. FMP sends the timestamp in milliseconds or nanoseconds
+// depending on the feed, so the unit is inferred from its magnitude.
+func (q WebsocketQuote) Time() time.Time {
+	return timeFromUnix(q.LastUpdated)
+}
+
+// Mid returns the price halfway between the bid and the ask. It is zero unless the quote has both.
+func (q WebsocketQuote) Mid() decimal.Decimal {
+	if !q.hasBidAsk() {
+		return decimal.Zero
+	}
+	return q.BidPrice.Add(q.AskPrice).Div(decimal.NewFromInt(2)) //nolint:mnd // halfway
+}
+
+// Spread returns the difference between the ask and the bid. It is zero unless the quote has both.
+func (q WebsocketQuote) Spread() decimal.Decimal {
+	if !q.hasBidAsk() {
+		return decimal.Zero
+	}
+	return q.AskPrice.Sub(q.BidPrice)
+}
+
+// Age returns the time elapsed between the last update and now.
+func (q WebsocketQuote) Age(now time.Time) time.Duration {
+	return now.Sub(q.Time())
+}
+
+func (q WebsocketQuote) hasBidAsk() bool {
+	return q.BidPrice.IsPositive() && q.AskPrice.IsPositive()
+}
+
 func (q WebsocketQuote) MarshalBinary() ([]byte, error) {
 	return json.Marshal(q)
 }
+
+func timeFromUnix(ts int64) time.Time {
+	const (
+		maxUnixSeconds = 1e11
+		maxUnixMillis  = 1e14
+		maxUnixMicros  = 1e17
+	)
+	switch {
+	case ts < maxUnixSeconds:
+		return time.Unix(ts, 0)
+	case ts < maxUnixMillis:
+		return time.UnixMilli(ts)
+	case ts < maxUnixMicros:
+		return time.UnixMicro(ts)
+	}
+	return time.Unix(0, ts)
+}
+
+// WebsocketCryptoQuote is a quote of the crypto feed. It has no last price, which comes with trades instead.
+type WebsocketCryptoQuote struct {
+	Symbol      string          `json:"s"`
+	Exchange    string          `json:"e"`
+	AskPrice    decimal.Decimal `json:"ap"`
+	AskSize     decimal.Decimal `json:"as"`
+	BidPrice    decimal.Decimal `json:"bp"`
+	BidSize     decimal.Decimal `json:"bs"`
+	LastUpdated int64           `json:"t"`
+}
+
+// Time returns the time of the last update.
+func (q WebsocketCryptoQuote) Time() time.Time {
+	return timeFromUnix(q.LastUpdated)
+}
+
+// Quote returns the crypto quote as a quote of the stock feed.
+func (q WebsocketCryptoQuote) Quote() WebsocketQuote {
+	return WebsocketQuote{
+		Symbol:      q.Symbol,
+		AskPrice:    q.AskPrice,
+		AskSize:     q.AskSize,
+		BidPrice:    q.BidPrice,
+		BidSize:     q.BidSize,
+		LastUpdated: q.LastUpdated,
+	}
+}
+
+// WebsocketForexQuote is a quote of the forex feed.
+type WebsocketForexQuote struct {
+	Symbol      string          `json:"s"`
+	AskPrice    decimal.Decimal `json:"ap"`
+	AskSize     decimal.Decimal `json:"as"`
+	BidPrice    decimal.Decimal `json:"bp"`
+	BidSize     decimal.Decimal `json:"bs"`
+	LastUpdated int64           `json:"t"`
+}
+
+// Time returns the time of the last update.
+func (q WebsocketForexQuote) Time() time.Time {
+	return timeFromUnix(q.LastUpdated)
+}
+
+// Quote returns the forex quote as a quote of the stock feed.
+func (q WebsocketForexQuote) Quote() WebsocketQuote {
+	return WebsocketQuote{
+		Symbol:      q.Symbol,
+		AskPrice:    q.AskPrice,
+		AskSize:     q.AskSize,
+		BidPrice:    q.BidPrice,
+		BidSize:     q.BidSize,
+		LastUpdated: q.LastUpdated,
+	}
+}
+
+// WebsocketTrade is a trade of the stock or crypto feed. The exchange is only set by the crypto feed.
+type WebsocketTrade struct {
+	Symbol      string          `json:"s"`
+	Exchange    string          `json:"e,omitempty"`
+	LastPrice   decimal.Decimal `json:"lp"`
+	LastSize    decimal.Decimal `json:"ls"`
+	LastUpdated int64           `json:"t"`
+}
+
+// Time returns the time of the trade.
+func (t WebsocketTrade) Time() time.Time {
+	return timeFromUnix(t.LastUpdated)
+}
+
+// WebsocketBreak reports a break in the trading of the symbol.
+type WebsocketBreak struct {
+	Symbol      string `json:"s"`
+	LastUpdated int64  `json:"t"`
+}
+
+// Time returns the time of the break.
+func (b WebsocketBreak) Time() time.Time {
+	return timeFromUnix(b.LastUpdated)
+}
+
+// WebsocketEvent is a market data message of a websocket feed, tagged by its feed and type. Exactly one of its
+// values is set: Quote, CryptoQuote or ForexQuote for quotes depending on the feed, Trade or Break otherwise.
+type WebsocketEvent struct {
+	Feed WebsocketFeed
+	Type WebsocketMessageType
+
+	Quote       *WebsocketQuote
+	CryptoQuote *WebsocketCryptoQuote
+	ForexQuote  *WebsocketForexQuote
+	Trade       *WebsocketTrade
+	Break       *WebsocketBreak
+}
+
+// Symbol returns the symbol of the message.
+func (e WebsocketEvent) Symbol() string {
+	switch {
+	case e.Quote != nil:
+		return e.Quote.Symbol
+	case e.CryptoQuote != nil:
+		return e.CryptoQuote.Symbol
+	case e.ForexQuote != nil:
+		return e.ForexQuote.Symbol
+	case e.Trade != nil:
+		return e.Trade.Symbol
+	case e.Break != nil:
+		return e.Break.Symbol
+	}
+	return ""
+}
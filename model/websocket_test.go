@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebsocketQuote_Time(t *testing.T) {
+	want := time.Date(2025, 3, 14, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		ts   int64
+	}{
+		{name: "seconds", ts: want.Unix()},
+		{name: "milliseconds", ts: want.UnixMilli()},
+		{name: "microseconds", ts: want.UnixMicro()},
+		{name: "nanoseconds", ts: want.UnixNano()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, want.Equal(WebsocketQuote{LastUpdated: tt.ts}.Time()))
+		})
+	}
+}
+
+func TestWebsocketFeedQuotes(t *testing.T) {
+	t.Run("crypto", func(t *testing.T) {
+		var q WebsocketCryptoQuote
+		require.NoError(t, json.Unmarshal([]byte(`{"s":"btcusd","t":1741966200000000000,"e":"binance","type":"Q","bs":1.5,"bp":96999.5,"as":0.2,"ap":97000.5}`), &q))
+		assert.Equal(t, "binance", q.Exchange)
+		assert.Equal(t, WebsocketQuote{
+			Symbol:      "btcusd",
+			AskPrice:    decimal.RequireFromString("97000.5"),
+			AskSize:     decimal.RequireFromString("0.2"),
+			BidPrice:    decimal.RequireFromString("96999.5"),
+			BidSize:     decimal.RequireFromString("1.5"),
+			LastUpdated: 1741966200000000000,
+		}, q.Quote())
+	})
+	t.Run("forex", func(t *testing.T) {
+		var q WebsocketForexQuote
+		require.NoError(t, json.Unmarshal([]byte(`{"s":"eurusd","t":1741966200000,"type":"Q","ap":1.0912,"as":1000000,"bp":1.091,"bs":1000000}`), &q))
+		assert.True(t, time.Date(2025, 3, 14, 15, 30, 0, 0, time.UTC).Equal(q.Time()))
+		assert.Equal(t, "eurusd", q.Quote().Symbol)
+		assert.True(t, decimal.RequireFromString("1.091").Equal(q.Quote().BidPrice))
+	})
+	t.Run("trade", func(t *testing.T) {
+		var trade WebsocketTrade
+		require.NoError(t, json.Unmarshal([]byte(`{"s":"aapl","t":1741966200000,"type":"T","lp":243.85,"ls":100}`), &trade))
+		assert.True(t, decimal.NewFromInt(100).Equal(trade.LastSize))
+		assert.Equal(t, "aapl", WebsocketEvent{Trade: &trade}.Symbol())
+	})
+}
@@ -1,6 +1,9 @@
 package types
 
-import "encoding/json"
+import (
+	"encoding"
+	"encoding/json"
+)
 
 type EmptyOr[T any] struct {
 	hasValue bool
@@ -30,3 +33,25 @@ func (e *EmptyOr[T]) UnmarshalJSON(data []byte) error {
 	e.hasValue = true
 	return json.Unmarshal(data, &e.value)
 }
+
+func (e *EmptyOr[T]) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		e.hasValue, e.value = false, nil
+		return nil
+	}
+	e.hasValue = true
+	e.value = new(T)
+	return unmarshalText(data, e.value)
+}
+
+// unmarshalText decodes text into v with its UnmarshalText method, as is into strings and as JSON otherwise.
+func unmarshalText(data []byte, v any) error {
+	switch v := v.(type) {
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(data)
+	case *string:
+		*v = string(data)
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
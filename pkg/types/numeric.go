@@ -24,3 +24,11 @@ func (t *ThousandSeparatedNumeric[T]) UnmarshalJSON(data []byte) error {
 	}
 	return json.Unmarshal([]byte(s), &t.value)
 }
+
+func (t *ThousandSeparatedNumeric[T]) UnmarshalText(data []byte) error {
+	s := strings.ReplaceAll(string(data), ",", "")
+	if len(s) == 0 {
+		return nil
+	}
+	return unmarshalText([]byte(s), &t.value)
+}
@@ -50,12 +50,17 @@ func (r *Range52w) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func ParseRange52w(r string, separator string) (Range52w, error) {
-	m, err := regexp.MatchString(RangePattern, r)
+func (r *Range52w) UnmarshalText(data []byte) error {
+	rng, err := ParseRange52w(string(data), "-")
 	if err != nil {
-		return Range52w{}, fmt.Errorf("invalid range: %w", err)
+		return fmt.Errorf("parsing range: %w", err)
 	}
-	if !m {
+	*r = rng
+	return nil
+}
+
+func ParseRange52w(r string, separator string) (Range52w, error) {
+	if !rangeRegexp.MatchString(r) {
 		return Range52w{}, fmt.Errorf("invalid range: %s", r)
 	}
 
@@ -40,7 +40,7 @@ func main() {
 	signal.Notify(interrupt, os.Interrupt)
 
 	logger.Info("subscribing to quotes feed...")
-	if err := client.Subscribe([]string{"AAPL"}); err != nil {
+	if err := client.Subscribe(ctx, []string{"AAPL"}); err != nil {
 		logger.Error("subscribing to AAPL quotes feed", slog.Any("error", err))
 		return
 	}